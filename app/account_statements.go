@@ -0,0 +1,186 @@
+package app
+
+import (
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/bank"
+
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// StatementsTopic is the entry of subscribe-modules which turns on the daily
+// "account_statement" messages.
+const StatementsTopic = "statements"
+
+const statementDateLayout = "2006-01-02"
+
+// AccountStatement summarizes the changes of an account's holdings during one UTC
+// day. The holdings are the available, locked and frozen coins of the account, so
+// freezing coins for an order or unlocking them changes nothing, only transfers do.
+// Opening + TotalIn - TotalOut always equals Closing, the totals are summed per
+// block, so coins received and spent in the same block cancel out. As every
+// transfer touches its accounts, Closing is the Opening of the account's next
+// statement.
+type AccountStatement struct {
+	Address  string    `json:"address"`
+	Date     string    `json:"date"`
+	Height   int64     `json:"height"`
+	Opening  sdk.Coins `json:"opening"`
+	Closing  sdk.Coins `json:"closing"`
+	TotalIn  sdk.Coins `json:"total_in"`
+	TotalOut sdk.Coins `json:"total_out"`
+}
+
+// accountStatements keeps the statements of the current day in memory, so a
+// restarted node only reports the changes after its restart for that day.
+type accountStatements struct {
+	date       string
+	height     int64
+	touched    map[string]sdk.AccAddress
+	statements map[string]*AccountStatement
+}
+
+func newAccountStatements() *accountStatements {
+	return &accountStatements{
+		touched:    make(map[string]sdk.AccAddress),
+		statements: make(map[string]*AccountStatement),
+	}
+}
+
+func (s *accountStatements) touch(addrs ...sdk.AccAddress) {
+	for _, addr := range addrs {
+		s.touched[string(addr)] = addr
+	}
+}
+
+// touchEvents marks the senders and recipients of the transfers in events
+func (s *accountStatements) touchEvents(events []abci.Event) {
+	for _, event := range events {
+		if event.Type != bank.EventTypeTransfer && event.Type != sdk.EventTypeMessage {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			key := string(attr.Key)
+			if key != bank.AttributeKeyRecipient && key != sdk.AttributeKeySender {
+				continue
+			}
+			if addr, err := sdk.AccAddressFromBech32(string(attr.Value)); err == nil {
+				s.touch(addr)
+			}
+		}
+	}
+}
+
+// update records the balances of the accounts touched in the block. balanceOf returns
+// the balance after the block and prevBalanceOf the one before it, which is only needed
+// for the first change of an account in a day. When the block starts a new day,
+// the statements of the previous day are returned ordered by address.
+func (s *accountStatements) update(date string, height int64,
+	balanceOf, prevBalanceOf func(sdk.AccAddress) sdk.Coins) (finished []AccountStatement) {
+
+	if s.date != date {
+		if s.date != "" {
+			finished = s.finish()
+		}
+		s.date = date
+		s.statements = make(map[string]*AccountStatement)
+	}
+	s.height = height
+
+	for key, addr := range s.touched {
+		closing := balanceOf(addr)
+		st, ok := s.statements[key]
+		if !ok {
+			opening := prevBalanceOf(addr)
+			st = &AccountStatement{
+				Address:  addr.String(),
+				Opening:  opening,
+				Closing:  opening,
+				TotalIn:  sdk.NewCoins(),
+				TotalOut: sdk.NewCoins(),
+			}
+			s.statements[key] = st
+		}
+		st.book(closing)
+	}
+	s.touched = make(map[string]sdk.AccAddress)
+	return
+}
+
+// book adds the changes from st.Closing to closing into the totals
+func (st *AccountStatement) book(closing sdk.Coins) {
+	for _, coin := range closing.Add(st.Closing) {
+		diff := closing.AmountOf(coin.Denom).Sub(st.Closing.AmountOf(coin.Denom))
+		if diff.IsPositive() {
+			st.TotalIn = st.TotalIn.Add(sdk.NewCoins(sdk.NewCoin(coin.Denom, diff)))
+		} else if diff.IsNegative() {
+			st.TotalOut = st.TotalOut.Add(sdk.NewCoins(sdk.NewCoin(coin.Denom, diff.Neg())))
+		}
+	}
+	st.Closing = closing
+}
+
+func (s *accountStatements) finish() []AccountStatement {
+	if len(s.statements) == 0 {
+		return nil
+	}
+	res := make([]AccountStatement, 0, len(s.statements))
+	for _, st := range s.statements {
+		st.Date = s.date
+		st.Height = s.height
+		res = append(res, *st)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Address < res[j].Address
+	})
+	return res
+}
+
+func (app *CetChainApp) pushAccountStatements(ctx sdk.Context) {
+	date := ctx.BlockHeader().Time.UTC().Format(statementDateLayout)
+	balanceOf := func(addr sdk.AccAddress) sdk.Coins {
+		return app.holdingsOf(ctx, addr)
+	}
+	var committedCtx *sdk.Context
+	prevBalanceOf := func(addr sdk.AccAddress) sdk.Coins {
+		if committedCtx == nil {
+			committedCtx = app.committedContext(ctx)
+		}
+		return app.holdingsOf(*committedCtx, addr)
+	}
+	finished := app.statements.update(date, ctx.BlockHeight(), balanceOf, prevBalanceOf)
+	for _, st := range finished {
+		app.appendPubMsgKV("account_statement", dex.SafeJSONMarshal(st))
+	}
+}
+
+// holdingsOf returns the available, locked and frozen coins of addr
+func (app *CetChainApp) holdingsOf(ctx sdk.Context, addr sdk.AccAddress) sdk.Coins {
+	coins := sdk.NewCoins()
+	if acc := app.accountKeeper.GetAccount(ctx, addr); acc != nil {
+		coins = acc.GetCoins()
+	}
+	if accx, ok := app.accountXKeeper.GetAccountX(ctx, addr); ok {
+		for _, locked := range accx.LockedCoins {
+			coins = coins.Add(sdk.NewCoins(locked.Coin))
+		}
+		coins = coins.Add(accx.FrozenCoins)
+	}
+	return coins
+}
+
+// committedContext returns a context on the last committed state, which the block
+// being executed has not changed yet. The genesis state is only committed with the
+// first block, so during it ctx itself is returned and its changes are part of the
+// Opening of the statements.
+func (app *CetChainApp) committedContext(ctx sdk.Context) *sdk.Context {
+	ms, err := app.cms.CacheMultiStoreWithVersion(app.LastBlockHeight())
+	if err != nil {
+		return &ctx
+	}
+	committed := ctx.WithMultiStore(ms)
+	return &committed
+}
@@ -0,0 +1,245 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestAccountStatementsUpdate(t *testing.T) {
+	addr := sdk.AccAddress([]byte("addr"))
+	balances := []sdk.Coins{
+		dex.NewCetCoins(100),
+		dex.NewCetCoins(70),
+		sdk.NewCoins(sdk.NewInt64Coin("cet", 90), sdk.NewInt64Coin("eth", 5)),
+		sdk.NewCoins(sdk.NewInt64Coin("cet", 95), sdk.NewInt64Coin("eth", 5)),
+	}
+	// committed is the balance before the block, current the one after it
+	committed, current := balances[0], balances[0]
+	balanceOf := func(sdk.AccAddress) sdk.Coins { return current }
+	prevBalanceOf := func(sdk.AccAddress) sdk.Coins { return committed }
+	s := newAccountStatements()
+	update := func(date string, height int64) []AccountStatement {
+		finished := s.update(date, height, balanceOf, prevBalanceOf)
+		committed = current
+		return finished
+	}
+
+	s.touch(addr)
+	current = balances[1]
+	require.Nil(t, update("2020-01-01", 10))
+	s.touch(addr)
+	current = balances[2]
+	require.Nil(t, update("2020-01-01", 11))
+	require.Nil(t, update("2020-01-01", 12))
+
+	finished := update("2020-01-02", 13)
+	require.Equal(t, 1, len(finished))
+	st := finished[0]
+	require.Equal(t, addr.String(), st.Address)
+	require.Equal(t, "2020-01-01", st.Date)
+	require.Equal(t, int64(12), st.Height)
+	require.Equal(t, balances[0], st.Opening)
+	require.Equal(t, balances[2], st.Closing)
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("cet", 20), sdk.NewInt64Coin("eth", 5)), st.TotalIn)
+	require.Equal(t, dex.NewCetCoins(30), st.TotalOut)
+	require.Equal(t, 0, len(s.statements))
+
+	// the next statement opens with the closing of the previous one
+	s.touch(addr)
+	current = balances[3]
+	require.Nil(t, update("2020-01-03", 14))
+	finished = update("2020-01-04", 15)
+	require.Equal(t, 1, len(finished))
+	require.Equal(t, "2020-01-03", finished[0].Date)
+	require.Equal(t, st.Closing, finished[0].Opening)
+	require.Equal(t, balances[3], finished[0].Closing)
+	require.Equal(t, dex.NewCetCoins(5), finished[0].TotalIn)
+	require.Equal(t, sdk.NewCoins(), finished[0].TotalOut)
+}
+
+func TestAccountStatementsTouchEvents(t *testing.T) {
+	_, _, from := testutil.KeyPubAddr()
+	_, _, to := testutil.KeyPubAddr()
+	events := sdk.Events{
+		sdk.NewEvent("transfer", sdk.NewAttribute("recipient", to.String())),
+		sdk.NewEvent(sdk.EventTypeMessage, sdk.NewAttribute(sdk.AttributeKeySender, from.String())),
+		sdk.NewEvent(sdk.EventTypeMessage, sdk.NewAttribute(sdk.AttributeKeyModule, "bankx")),
+	}
+
+	s := newAccountStatements()
+	s.touchEvents(events.ToABCIEvents())
+	require.Equal(t, 2, len(s.touched))
+	require.Equal(t, from, s.touched[string(from)])
+	require.Equal(t, to, s.touched[string(to)])
+}
+
+func TestPushAccountStatements(t *testing.T) {
+	_, _, toAddr := testutil.KeyPubAddr()
+	key, _, fromAddr := testutil.KeyPubAddr()
+	coins := dex.NewCetCoins(30000000000)
+	acc0 := auth.BaseAccount{Address: fromAddr, Coins: coins}
+
+	app := initAppWithBaseAccounts(acc0)
+	app.statements = newAccountStatements()
+
+	day1 := time.Date(2020, 1, 1, 23, 0, 0, 0, time.UTC)
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 1, Time: day1}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 2, Time: day1.Add(time.Minute)}})
+	msg := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1000000000), 0)
+	tx := newStdTxBuilder().
+		Msgs(msg).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 2})
+	app.Commit()
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 3, Time: day1.Add(time.Hour)}})
+	app.EndBlock(abci.RequestEndBlock{Height: 3})
+
+	statements := pushedStatements(t, app)
+	from := statements[fromAddr.String()]
+	require.Equal(t, "2020-01-01", from.Date)
+	require.Equal(t, int64(2), from.Height)
+	require.Equal(t, coins, from.Opening)
+	require.Equal(t, dex.NewCetCoins(30000000000-1000000000-100), from.Closing)
+	require.Equal(t, dex.NewCetCoins(1000000000+100), from.TotalOut)
+	to := statements[toAddr.String()]
+	require.Equal(t, sdk.NewCoins(), to.Opening)
+	// the activation fee is deducted from the first transfer to a new account
+	require.Equal(t, dex.NewCetCoins(900000000), to.TotalIn)
+	require.Equal(t, dex.NewCetCoins(900000000), to.Closing)
+}
+
+func TestAccountStatementsUnlockInEndBlock(t *testing.T) {
+	fromKey, _, fromAddr := testutil.KeyPubAddr()
+	toKey, _, toAddr := testutil.KeyPubAddr()
+	coins := dex.NewCetCoins(30000000000)
+	app := initAppWithBaseAccounts(
+		auth.BaseAccount{Address: fromAddr, Coins: coins},
+		auth.BaseAccount{Address: toAddr, Coins: coins})
+	app.statements = newAccountStatements()
+
+	day1 := time.Date(2020, 1, 1, 22, 0, 0, 0, time.UTC)
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 1, Time: day1}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 2, Time: day1.Add(time.Minute)}})
+	unlockTime := day1.Add(30 * time.Minute).Unix()
+	lockedSend := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1000000000), unlockTime)
+	tx := newStdTxBuilder().
+		Msgs(lockedSend).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, fromKey).Build()
+	require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 2})
+	app.Commit()
+
+	// authx unlocks the coins in EndBlock, which does not change the holdings
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 3, Time: day1.Add(time.Hour)}})
+	send := bankx.NewMsgSend(toAddr, fromAddr, dex.NewCetCoins(100000000), 0)
+	tx = newStdTxBuilder().
+		Msgs(send).GasAndFee(1000000, 100).AccNumSeqKey(1, 0, toKey).Build()
+	require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+	app.EndBlock(abci.RequestEndBlock{Height: 3})
+	app.Commit()
+
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 4, Time: day1.Add(3 * time.Hour)}})
+	app.EndBlock(abci.RequestEndBlock{Height: 4})
+
+	to := pushedStatements(t, app)[toAddr.String()]
+	require.Equal(t, "2020-01-01", to.Date)
+	require.Equal(t, coins, to.Opening)
+	require.Equal(t, dex.NewCetCoins(30000000000-100000000-100+1000000000), to.Closing)
+	require.Equal(t, dex.NewCetCoins(1000000000), to.TotalIn)
+	require.Equal(t, dex.NewCetCoins(100000000+100), to.TotalOut)
+}
+
+func TestAccountStatementsUntouchedDay(t *testing.T) {
+	fromKey, _, fromAddr := testutil.KeyPubAddr()
+	toKey, _, toAddr := testutil.KeyPubAddr()
+	coins := dex.NewCetCoins(30000000000)
+	app := initAppWithBaseAccounts(
+		auth.BaseAccount{Address: fromAddr, Coins: coins},
+		auth.BaseAccount{Address: toAddr, Coins: coins})
+	app.statements = newAccountStatements()
+	nextBlock := func(height int64, blockTime time.Time, txs ...auth.StdTx) map[string]AccountStatement {
+		app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: height, Time: blockTime}})
+		for _, tx := range txs {
+			require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+		}
+		app.EndBlock(abci.RequestEndBlock{Height: height})
+		statements := pushedStatements(t, app)
+		app.Commit()
+		return statements
+	}
+
+	day1 := time.Date(2020, 1, 1, 22, 0, 0, 0, time.UTC)
+	nextBlock(1, day1)
+	unlockTime := day1.Add(3 * time.Hour).Unix()
+	lockedSend := bankx.NewMsgSend(fromAddr, toAddr, dex.NewCetCoins(1000000000), unlockTime)
+	nextBlock(2, day1.Add(time.Minute), newStdTxBuilder().
+		Msgs(lockedSend).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, fromKey).Build())
+	// the coins are unlocked on day 2, when toAddr is not touched
+	day1Statements := nextBlock(3, day1.Add(4*time.Hour))
+	day2Statements := nextBlock(4, day1.Add(36*time.Hour))
+	send := bankx.NewMsgSend(toAddr, fromAddr, dex.NewCetCoins(100000000), 0)
+	nextBlock(5, day1.Add(37*time.Hour), newStdTxBuilder().
+		Msgs(send).GasAndFee(1000000, 100).AccNumSeqKey(1, 0, toKey).Build())
+	day3Statements := nextBlock(6, day1.Add(60*time.Hour))
+
+	to1 := day1Statements[toAddr.String()]
+	require.Equal(t, "2020-01-01", to1.Date)
+	require.Equal(t, coins, to1.Opening)
+	require.Equal(t, dex.NewCetCoins(31000000000), to1.Closing)
+	require.Equal(t, dex.NewCetCoins(1000000000), to1.TotalIn)
+	_, ok := day2Statements[toAddr.String()]
+	require.False(t, ok)
+	to3 := day3Statements[toAddr.String()]
+	require.Equal(t, "2020-01-03", to3.Date)
+	require.Equal(t, to1.Closing, to3.Opening)
+	require.Equal(t, sdk.NewCoins(), to3.TotalIn)
+	require.Equal(t, dex.NewCetCoins(100000000+100), to3.TotalOut)
+	require.Equal(t, dex.NewCetCoins(31000000000-100000000-100), to3.Closing)
+}
+
+func TestHoldingsOf(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	app := initAppWithBaseAccounts(auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(100)})
+	ctx := app.NewContext(false, abci.Header{Height: 1})
+	accx := authx.NewAccountXWithAddress(addr)
+	accx.LockedCoins = authx.LockedCoins{
+		authx.NewLockedCoin("cet", sdk.NewInt(20), 1000),
+		authx.NewLockedCoin("eth", sdk.NewInt(5), 1000),
+	}
+	// the coins frozen by an order
+	accx.FrozenCoins = dex.NewCetCoins(30)
+	app.accountXKeeper.SetAccountX(ctx, accx)
+
+	require.Equal(t, sdk.NewCoins(sdk.NewInt64Coin("cet", 150), sdk.NewInt64Coin("eth", 5)), app.holdingsOf(ctx, addr))
+}
+
+// pushedStatements returns the statements sent in the current block by address
+func pushedStatements(t *testing.T, app *CetChainApp) map[string]AccountStatement {
+	statements := make(map[string]AccountStatement)
+	for _, msg := range app.pubMsgs {
+		if string(msg.Key) == "account_statement" {
+			var st AccountStatement
+			require.Nil(t, json.Unmarshal(msg.Value, &st))
+			statements[st.Address] = st
+		}
+	}
+	return statements
+}
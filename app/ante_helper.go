@@ -1,6 +1,8 @@
 package app
 
 import (
+	"fmt"
+
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/x/distribution"
 	"github.com/cosmos/cosmos-sdk/x/gov"
@@ -12,20 +14,34 @@ import (
 	"github.com/coinexchain/cet-sdk/modules/bankx"
 	"github.com/coinexchain/cet-sdk/modules/distributionx"
 	"github.com/coinexchain/cet-sdk/modules/incentive"
+	"github.com/coinexchain/cet-sdk/modules/market"
 	"github.com/coinexchain/cet-sdk/modules/stakingx"
 )
 
+const (
+	CodeSpaceMempoolFilter sdk.CodespaceType = "mempool_filter"
+	CodeMarketNotTradable  sdk.CodeType      = 2200
+)
+
+func errMarketNotTradable(pair, reason string) sdk.Error {
+	return sdk.NewError(CodeSpaceMempoolFilter, CodeMarketNotTradable,
+		fmt.Sprintf("Orders of %s are not accepted: %s", pair, reason))
+}
+
 var _ authx.AnteHelper = anteHelper{}
 
 type anteHelper struct {
 	accountXKeeper authx.AccountXKeeper
 	stakingXKeeper stakingx.Keeper
+	marketKeeper   market.Keeper
 }
 
-func newAnteHelper(accountXKeeper authx.AccountXKeeper, stakingXKeeper stakingx.Keeper) anteHelper {
+func newAnteHelper(accountXKeeper authx.AccountXKeeper, stakingXKeeper stakingx.Keeper,
+	marketKeeper market.Keeper) anteHelper {
 	return anteHelper{
 		accountXKeeper: accountXKeeper,
 		stakingXKeeper: stakingXKeeper,
+		marketKeeper:   marketKeeper,
 	}
 }
 
@@ -64,6 +80,14 @@ func (ah anteHelper) CheckMsg(ctx sdk.Context, msg sdk.Msg, memo string) sdk.Err
 	case gov.MsgDeposit:
 		return ah.checkMsgDeposit(msg)
 
+	case market.MsgCreateOrder:
+		// only keeps such orders out of the mempool, in DeliverTx they
+		// are rejected by the market handler, which also charges the fee
+		if ctx.IsCheckTx() {
+			return ah.checkMarketTradable(ctx, msg.TradingPair)
+		}
+		return nil
+
 	}
 
 	return nil
@@ -125,6 +149,17 @@ func (ah anteHelper) checkMsgDeposit(msg gov.MsgDeposit) sdk.Error {
 	return nil
 }
 
+func (ah anteHelper) checkMarketTradable(ctx sdk.Context, pair string) sdk.Error {
+	if _, err := ah.marketKeeper.GetMarketInfo(ctx, pair); err != nil {
+		return errMarketNotTradable(pair, "no such market")
+	}
+	stock, money := market.SplitSymbol(pair)
+	if ah.marketKeeper.IsTokenForbidden(ctx, stock) || ah.marketKeeper.IsTokenForbidden(ctx, money) {
+		return errMarketNotTradable(pair, "token is forbidden by its issuer")
+	}
+	return nil
+}
+
 func checkAddr(msg sdk.Msg) sdk.Error {
 	signers := msg.GetSigners()
 	for _, signer := range signers {
@@ -5,7 +5,6 @@ import (
 	"fmt"
 	"io"
 	"os"
-	"strconv"
 	"sync"
 
 	"github.com/cosmos/cosmos-sdk/server"
@@ -19,6 +18,7 @@ import (
 
 	bam "github.com/cosmos/cosmos-sdk/baseapp"
 	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/store"
 	sdk "github.com/cosmos/cosmos-sdk/types"
 	"github.com/cosmos/cosmos-sdk/types/module"
 	"github.com/cosmos/cosmos-sdk/version"
@@ -183,13 +183,21 @@ type CetChainApp struct {
 	once            *sync.Once
 
 	enableUnconfirmedLimit bool
+	enableStoreStats       bool
 	currBlockTime          int64
 	account2UnconfirmedTx  *Account2UnconfirmedTx
 
 	// the module manager
 	mm *module.Manager
 
-	pubMsgs []PubMsg
+	pubMsgs         []PubMsg
+	enableMsgDigest bool
+	schemaAnnounced bool
+	heightInfo      NewHeightInfo
+	heightInfoIdx   int
+	statements      *accountStatements
+	// cms is the CommitMultiStore of BaseApp, which keeps it unexported
+	cms sdk.CommitMultiStore
 	plugin.Holder
 }
 
@@ -200,14 +208,24 @@ func NewCetChainApp(logger log.Logger, db dbm.DB, traceStore io.Writer, loadLate
 	cdc := MakeCodec()
 
 	txDecoder := auth.DefaultTxDecoder(cdc)
+	// the store is set before the other options, which may configure its pruning
+	cms := store.NewCommitMultiStore(db)
+	baseAppOptions = append([]func(*bam.BaseApp){func(bApp *bam.BaseApp) { bApp.SetCMS(cms) }}, baseAppOptions...)
 	bApp := bam.NewBaseApp(appName, logger, db, txDecoder, baseAppOptions...)
 	bApp.SetCommitMultiStoreTracer(traceStore)
 	bApp.SetAppVersion(version.Version)
 	bam.SetHaltHeight(viper.GetUint64(server.FlagHaltHeight))(bApp)
+	bam.SetHaltTime(viper.GetUint64(server.FlagHaltTime))(bApp)
+
+	conf, err := LoadDexConfig()
+	if err != nil {
+		cmn.Exit(err.Error())
+	}
 
 	app := newCetChainApp(bApp, cdc, invCheckPeriod, txDecoder)
+	app.cms = cms
 	app.initPubMsgBuf()
-	app.initMsgQue()
+	app.initMsgQue(conf)
 	app.initKeepers(invCheckPeriod)
 	app.initModules()
 	app.mountStores()
@@ -215,7 +233,7 @@ func NewCetChainApp(logger log.Logger, db dbm.DB, traceStore io.Writer, loadLate
 	app.WaitPluginToggleSignal(logger)
 
 	ah := authx.NewAnteHandler(app.accountKeeper, app.supplyKeeper, app.accountXKeeper,
-		newAnteHelper(app.accountXKeeper, app.stakingXKeeper))
+		newAnteHelper(app.accountXKeeper, app.stakingXKeeper, app.marketKeeper))
 
 	app.SetInitChainer(app.initChainer)
 	app.SetBeginBlocker(app.beginBlocker)
@@ -229,23 +247,13 @@ func NewCetChainApp(logger log.Logger, db dbm.DB, traceStore io.Writer, loadLate
 		}
 	}
 
-	unconfirmedTxLimitTime, ok := os.LookupEnv("COINEX_UNCONFIRMED_TX_LIMIT_TIME")
-	var limitTime int64
-	var err error
-	if ok {
-		limitTime, err = strconv.ParseInt(unconfirmedTxLimitTime, 10, 64)
-		if err != nil {
-			limitTime = -1
-		}
-	} else {
-		limitTime = DefaultLimitTime
-	}
-	if limitTime > 0 {
+	if conf.UnconfirmedTxLimitTime > 0 {
 		app.enableUnconfirmedLimit = true
-		app.account2UnconfirmedTx = NewAccount2UnconfirmedTx(limitTime)
+		app.account2UnconfirmedTx = NewAccount2UnconfirmedTx(conf.UnconfirmedTxLimitTime)
 	} else {
 		app.enableUnconfirmedLimit = false
 	}
+	app.enableStoreStats = conf.EnableStoreStats
 	return app
 }
 
@@ -276,10 +284,14 @@ func newCetChainApp(bApp *bam.BaseApp, cdc *codec.Codec, invCheckPeriod uint, tx
 	}
 }
 
-func (app *CetChainApp) initMsgQue() {
-	app.msgQueProducer = msgqueue.NewProducer(app.Logger()) // TODO
-	if isOpenTs() {
-		conf, err := initConf()
+func (app *CetChainApp) initMsgQue(conf DexConfig) {
+	app.msgQueProducer = msgqueue.NewProducerFromConfig(conf.Brokers, conf.SubscribeModules, conf.FeatureToggle, app.Logger())
+	app.enableMsgDigest = conf.MsgQueueDigest
+	if app.msgQueProducer.IsSubscribed(StatementsTopic) {
+		app.statements = newAccountStatements()
+	}
+	if isOpenTs(conf.Brokers) {
+		conf, err := initConf(conf.Brokers)
 		if err != nil {
 			panic(fmt.Sprintf("init trade-server conf faild, err : %s\b", err.Error()))
 		}
@@ -487,6 +499,7 @@ func (app *CetChainApp) initModules() {
 	app.mm.RegisterInvariants(&app.crisisKeeper)
 
 	app.registerRoutesWithOrder(modules)
+	app.QueryRouter().AddRoute(QuerierRoute, newQuerier(app))
 }
 
 func (app *CetChainApp) createAppModules() []module.AppModule {
@@ -554,12 +567,18 @@ func (app *CetChainApp) registerRoutesWithOrder(modules []module.AppModule) {
 
 // initialize BaseApp
 func (app *CetChainApp) mountStores() {
-	app.MountStores(app.keyMain, app.keyAccount, app.keySupply, app.keyStaking, app.keyDistr,
+	app.MountStores(app.tkeyParams, app.tkeyStaking)
+	for _, key := range app.kvStoreKeys() {
+		app.MountStores(key)
+	}
+}
+
+func (app *CetChainApp) kvStoreKeys() []*sdk.KVStoreKey {
+	return []*sdk.KVStoreKey{app.keyMain, app.keyAccount, app.keySupply, app.keyStaking, app.keyDistr,
 		app.keySlashing, app.keyGov, app.keyParams,
-		app.tkeyParams, app.tkeyStaking,
 		app.keyAccountX, app.keyAsset, app.keyMarket, app.keyIncentive,
 		app.keyBancor, app.keyAlias, app.keyComment, app.keyStakingX,
-	)
+	}
 }
 
 // application updates every begin block
@@ -571,6 +590,9 @@ func (app *CetChainApp) beginBlocker(ctx sdk.Context, req abci.RequestBeginBlock
 		app.pushNewHeightInfo(ctx)
 	}
 	ret := app.mm.BeginBlock(ctx, req)
+	if app.statements != nil {
+		app.statements.touchEvents(ret.Events)
+	}
 	if app.msgQueProducer.IsOpenToggle() {
 		ret.Events = collectKafkaEvents(ret.Events, app)
 		app.notifyBeginBlock(ret.Events)
@@ -586,9 +608,17 @@ func (app *CetChainApp) beginBlocker(ctx sdk.Context, req abci.RequestBeginBlock
 // nolint: unparam
 func (app *CetChainApp) endBlocker(ctx sdk.Context, req abci.RequestEndBlock) abci.ResponseEndBlock {
 	ret := app.mm.EndBlock(ctx, req)
+	if app.statements != nil {
+		app.statements.touchEvents(ret.Events)
+		app.pushAccountStatements(ctx)
+	}
 	if app.msgQueProducer.IsOpenToggle() {
 		ret.Events = collectKafkaEvents(ret.Events, app)
 		app.notifyEndBlock(ret.Events)
+		app.updateNewHeightInfo(ctx)
+		if app.msgQueProducer.IsSubscribed(TradeSummaryTopic) {
+			app.pushTradeSummaries(ctx.BlockHeight())
+		}
 	}
 	return ret
 }
@@ -636,6 +666,22 @@ func (app *CetChainApp) appendPubEvent(event abci.Event) {
 func (app *CetChainApp) appendPubMsgKV(key string, val []byte) {
 	app.pubMsgs = append(app.pubMsgs, PubMsg{Key: []byte(key), Value: val})
 }
+func (app *CetChainApp) getSchemaVersionMsg() []byte {
+	info := SchemaVersionInfo{
+		SchemaVersion: MsgQueueSchemaVersion,
+		NodeVersion:   version.Version,
+		Height:        app.height,
+	}
+	return dex.SafeJSONMarshal(info)
+}
+func (app *CetChainApp) getCommitMsg() []byte {
+	info := CommitInfo{SchemaVersion: MsgQueueSchemaVersion}
+	if app.enableMsgDigest {
+		info.Height = app.height
+		info.MsgsDigest = digestPubMsgs(app.pubMsgs)
+	}
+	return dex.SafeJSONMarshal(info)
+}
 
 /* "override" ABCI methods */
 
@@ -706,12 +752,23 @@ func (app *CetChainApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDelive
 
 	ret := app.BaseApp.DeliverTx(req)
 
+	if formatOK && app.statements != nil {
+		// the fees are deducted even if the tx fails
+		app.statements.touch(stdTx.GetSigners()...)
+		if ret.Code == uint32(sdk.CodeOK) {
+			app.statements.touchEvents(ret.Events)
+		}
+	}
+
 	if app.msgQueProducer.IsOpenToggle() {
 		if formatOK {
 			app.notifyTx(req, stdTx, ret)
 		}
 		if ret.Code == uint32(sdk.CodeOK) {
 			ret.Events = collectKafkaEvents(ret.Events, app)
+			if formatOK {
+				app.countIssuedTokens(stdTx)
+			}
 		} else {
 			ret.Events = discardKafkaEvents(ret.Events)
 		}
@@ -726,10 +783,14 @@ func (app *CetChainApp) DeliverTx(req abci.RequestDeliverTx) abci.ResponseDelive
 
 func (app *CetChainApp) Commit() abci.ResponseCommit {
 	if app.msgQueProducer.IsOpenToggle() {
+		if !app.schemaAnnounced {
+			app.msgQueProducer.SendMsg([]byte("schema_version"), app.getSchemaVersionMsg())
+			app.schemaAnnounced = true
+		}
 		for _, msg := range app.pubMsgs {
 			app.msgQueProducer.SendMsg(msg.Key, msg.Value)
 		}
-		app.msgQueProducer.SendMsg([]byte("commit"), []byte("{}"))
+		app.msgQueProducer.SendMsg([]byte("commit"), app.getCommitMsg())
 	}
 	if app.enableUnconfirmedLimit {
 		app.account2UnconfirmedTx.CommitRemove(app.currBlockTime)
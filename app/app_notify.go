@@ -15,7 +15,10 @@ import (
 	distrtypes "github.com/cosmos/cosmos-sdk/x/distribution/types"
 	sltypes "github.com/cosmos/cosmos-sdk/x/slashing/types"
 	stypes "github.com/cosmos/cosmos-sdk/x/staking/types"
+	"github.com/cosmos/cosmos-sdk/x/supply"
 
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/market"
 	dex "github.com/coinexchain/cet-sdk/types"
 )
 
@@ -30,24 +33,146 @@ type TxExtraInfo struct {
 	Codespace string       `json:"codespace,omitempty"`
 }
 
+// NewHeightInfo is the first message of a block. Its counters summarize the whole
+// block, they are filled in EndBlock, before any message of the block is sent.
+// The order counters only count the messages of a subscribed market module.
+// OrdersFilled counts the distinct orders with a fill, both sides of a deal are
+// counted and an order filled by several deals is counted once.
 type NewHeightInfo struct {
-	ChainID       string       `json:"chain_id"`
-	Height        int64        `json:"height"`
-	TimeStamp     int64        `json:"timestamp"`
-	LastBlockHash cmn.HexBytes `json:"last_block_hash"`
+	SchemaVersion  string       `json:"schema_version"`
+	ChainID        string       `json:"chain_id"`
+	Height         int64        `json:"height"`
+	TimeStamp      int64        `json:"timestamp"`
+	LastBlockHash  cmn.HexBytes `json:"last_block_hash"`
+	OrdersCreated  int          `json:"orders_created"`
+	OrdersFilled   int          `json:"orders_filled"`
+	OrdersCanceled int          `json:"orders_canceled"`
+	TokensIssued   int          `json:"tokens_issued"`
+	FeesCollected  sdk.Coins    `json:"fees_collected"`
 }
 
+// the keys and reason of the market module's msgqueue messages
+const (
+	createOrderInfoKey     = "create_order_info"
+	fillOrderInfoKey       = "fill_order_info"
+	cancelOrderInfoKey     = "del_order_info"
+	cancelOrderByAllFilled = "The order was fully filled"
+)
+
 func (app *CetChainApp) pushNewHeightInfo(ctx sdk.Context) {
-	msg := NewHeightInfo{
+	app.heightInfo = NewHeightInfo{
+		SchemaVersion: MsgQueueSchemaVersion,
 		ChainID:       ctx.BlockHeader().ChainID,
 		Height:        ctx.BlockHeight(),
 		TimeStamp:     ctx.BlockHeader().Time.Unix(),
 		LastBlockHash: ctx.BlockHeader().LastBlockId.Hash,
 	}
-	bytes := dex.SafeJSONMarshal(msg)
+	app.heightInfoIdx = len(app.pubMsgs)
+	bytes := dex.SafeJSONMarshal(app.heightInfo)
 	app.appendPubMsgKV("height_info", bytes)
 }
 
+// TradeSummaryTopic is the entry of subscribe-modules which turns on the "trade_summary"
+// messages. They are built from the fill messages, so market must be subscribed too.
+const TradeSummaryTopic = "trade_summary"
+
+// TradeSummary aggregates the trades of one trading pair in one block, the prices
+// are the fill prices in the order of matching
+type TradeSummary struct {
+	TradingPair string  `json:"trading_pair"`
+	Height      int64   `json:"height"`
+	Open        sdk.Dec `json:"open"`
+	High        sdk.Dec `json:"high"`
+	Low         sdk.Dec `json:"low"`
+	Close       sdk.Dec `json:"close"`
+	StockVolume sdk.Int `json:"stock_volume"`
+	MoneyVolume sdk.Int `json:"money_volume"`
+	TradeCount  int     `json:"trade_count"`
+}
+
+// pushTradeSummaries sends a "trade_summary" for each pair traded in the block. Each
+// trade has a fill message for both orders, only the seller's one is counted.
+func (app *CetChainApp) pushTradeSummaries(height int64) {
+	var summaries []*TradeSummary
+	pairs := make(map[string]*TradeSummary)
+	for _, msg := range app.pubMsgs {
+		if string(msg.Key) != fillOrderInfoKey {
+			continue
+		}
+		var fill market.FillOrderInfo
+		if json.Unmarshal(msg.Value, &fill) != nil || fill.Side != market.SELL {
+			continue
+		}
+		sum, ok := pairs[fill.TradingPair]
+		if !ok {
+			sum = &TradeSummary{
+				TradingPair: fill.TradingPair,
+				Height:      height,
+				Open:        fill.FillPrice,
+				High:        fill.FillPrice,
+				Low:         fill.FillPrice,
+				StockVolume: sdk.ZeroInt(),
+				MoneyVolume: sdk.ZeroInt(),
+			}
+			pairs[fill.TradingPair] = sum
+			summaries = append(summaries, sum)
+		}
+		if fill.FillPrice.GT(sum.High) {
+			sum.High = fill.FillPrice
+		}
+		if fill.FillPrice.LT(sum.Low) {
+			sum.Low = fill.FillPrice
+		}
+		sum.Close = fill.FillPrice
+		sum.StockVolume = sum.StockVolume.AddRaw(fill.CurrStock)
+		sum.MoneyVolume = sum.MoneyVolume.AddRaw(fill.CurrMoney)
+		sum.TradeCount++
+	}
+	for _, sum := range summaries {
+		app.appendPubMsgKV("trade_summary", dex.SafeJSONMarshal(sum))
+	}
+}
+
+func (app *CetChainApp) countIssuedTokens(stdTx auth.StdTx) {
+	for _, msg := range stdTx.Msgs {
+		if _, ok := msg.(asset.MsgIssueToken); ok {
+			app.heightInfo.TokensIssued++
+		}
+	}
+}
+
+// updateNewHeightInfo rewrites the "height_info" message with the counters of the block.
+// The fee collector is emptied by distribution in BeginBlock, so at the end of the block
+// it holds the fees collected in this block.
+func (app *CetChainApp) updateNewHeightInfo(ctx sdk.Context) {
+	info := &app.heightInfo
+	filled := make(map[string]bool)
+	for _, msg := range app.pubMsgs[app.heightInfoIdx+1:] {
+		switch string(msg.Key) {
+		case createOrderInfoKey:
+			info.OrdersCreated++
+		case fillOrderInfoKey:
+			var fill market.FillOrderInfo
+			if json.Unmarshal(msg.Value, &fill) == nil && !filled[fill.OrderID] {
+				filled[fill.OrderID] = true
+				info.OrdersFilled++
+			}
+		case cancelOrderInfoKey:
+			var cancel market.CancelOrderInfo
+			if json.Unmarshal(msg.Value, &cancel) == nil && cancel.DelReason != cancelOrderByAllFilled {
+				info.OrdersCanceled++
+			}
+		}
+	}
+	// not GetModuleAccount of supplyKeeper, which creates a missing account and would
+	// change the state only on the nodes with msgqueue turned on
+	info.FeesCollected = sdk.Coins{}
+	if acc := app.accountKeeper.GetAccount(ctx, supply.NewModuleAddress(auth.FeeCollectorName)); acc != nil {
+		info.FeesCollected = acc.GetCoins()
+	}
+	app.pubMsgs[app.heightInfoIdx].Value = dex.SafeJSONMarshal(info)
+}
+
 type TransferRecord struct {
 	Sender    string `json:"sender"`
 	Recipient string `json:"recipient"`
@@ -0,0 +1,150 @@
+package app
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestNewHeightInfoCounters(t *testing.T) {
+	key, _, addr := testutil.KeyPubAddr()
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1e16)}
+
+	// the modules get the producer when the app is created
+	viper.Set("brokers", []string{"nop"})
+	viper.Set("subscribe-modules", "market")
+	viper.Set("feature-toggle", true)
+	defer viper.Reset()
+	app := initAppWithAccounts(acc)
+	// distribution empties the fee collector from height 2 on
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 1}})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{ChainID: testChainID, Height: 2}})
+
+	stock, money := "hinfo", "cet"
+	newOrder := func(side byte) market.MsgCreateOrder {
+		return market.MsgCreateOrder{
+			Sender:         addr,
+			TradingPair:    stock + market.SymbolSeparator + money,
+			OrderType:      market.LimitOrder,
+			PricePrecision: 8,
+			Price:          100,
+			Quantity:       10000000,
+			Side:           side,
+			TimeInForce:    market.GTE,
+		}
+	}
+	msgs := []sdk.Msg{
+		asset.NewMsgIssueToken(stock, stock, sdk.NewInt(1e16), addr,
+			false, false, false, false, "", "", asset.TestIdentityString),
+		market.MsgCreateTradingPair{Stock: stock, Money: money, Creator: addr, PricePrecision: 8},
+		newOrder(market.SELL),
+		newOrder(market.BUY),
+		newOrder(market.SELL),
+	}
+	for seq, msg := range msgs {
+		tx := newStdTxBuilder().
+			Msgs(msg).GasAndFee(9000000, 100).AccNumSeqKey(0, uint64(seq), key).Build()
+		require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+	}
+	ctx := app.NewContext(false, abci.Header{Height: 2})
+	orders := app.marketKeeper.GetAllOrders(ctx)
+	require.Equal(t, 3, len(orders))
+	for _, order := range orders {
+		if order.Sequence == 5 {
+			tx := newStdTxBuilder().
+				Msgs(market.MsgCancelOrder{Sender: addr, OrderID: order.OrderID()}).
+				GasAndFee(9000000, 100).AccNumSeqKey(0, 5, key).Build()
+			require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+		}
+	}
+	app.EndBlock(abci.RequestEndBlock{Height: 2})
+
+	require.Equal(t, "height_info", string(app.pubMsgs[0].Key))
+	var info NewHeightInfo
+	require.Nil(t, json.Unmarshal(app.pubMsgs[0].Value, &info))
+	require.Equal(t, MsgQueueSchemaVersion, info.SchemaVersion)
+	require.Equal(t, int64(2), info.Height)
+	require.Equal(t, 3, info.OrdersCreated)
+	require.Equal(t, 2, info.OrdersFilled)
+	require.Equal(t, 1, info.OrdersCanceled)
+	require.Equal(t, 1, info.TokensIssued)
+	// 6 tx fees, the issue and pair creation fees, the minimum commission of
+	// the 2 filled orders and the zero-deal commission of the canceled one
+	marketParams := app.marketKeeper.GetParams(ctx)
+	fees := 6*100 + app.assetKeeper.GetParams(ctx).GetIssueTokenFee(stock) + marketParams.CreateMarketFee +
+		2*marketParams.MarketFeeMin + marketParams.FeeForZeroDeal
+	require.Equal(t, dex.NewCetCoins(fees), info.FeesCollected)
+	// trade_summary has its own entry in subscribe-modules
+	for _, msg := range app.pubMsgs {
+		require.NotEqual(t, "trade_summary", string(msg.Key))
+	}
+}
+
+func TestNewHeightInfoOrdersFilled(t *testing.T) {
+	app := initAppWithAccounts()
+	ctx := app.NewContext(false, abci.Header{Height: 2})
+	app.pushNewHeightInfo(ctx)
+	for _, id := range []string{"a-1", "b-1", "a-1", "c-1", "a-1", "b-1"} {
+		info := market.FillOrderInfo{OrderID: id, TradingPair: "abc/cet"}
+		app.appendPubMsgKV(fillOrderInfoKey, dex.SafeJSONMarshal(info))
+	}
+	app.updateNewHeightInfo(ctx)
+
+	var info NewHeightInfo
+	require.Nil(t, json.Unmarshal(app.pubMsgs[app.heightInfoIdx].Value, &info))
+	// three deals, a-1 is filled by all of them and b-1 by two
+	require.Equal(t, 3, info.OrdersFilled)
+}
+
+func TestPushTradeSummaries(t *testing.T) {
+	app := &CetChainApp{}
+	fill := func(pair string, side byte, price string, stock, money int64) {
+		info := market.FillOrderInfo{TradingPair: pair, Side: side,
+			FillPrice: sdk.MustNewDecFromStr(price), CurrStock: stock, CurrMoney: money}
+		app.appendPubMsgKV(fillOrderInfoKey, dex.SafeJSONMarshal(info))
+	}
+	fill("abc/cet", market.SELL, "1.5", 10, 15)
+	fill("abc/cet", market.BUY, "1.5", 10, 15)
+	fill("xyz/cet", market.SELL, "3", 1, 3)
+	fill("xyz/cet", market.BUY, "3", 1, 3)
+	fill("abc/cet", market.SELL, "2", 10, 20)
+	fill("abc/cet", market.BUY, "2", 10, 20)
+	fill("abc/cet", market.SELL, "1", 20, 20)
+	fill("abc/cet", market.BUY, "1", 20, 20)
+	app.pushTradeSummaries(7)
+
+	var summaries []TradeSummary
+	for _, msg := range app.pubMsgs {
+		if string(msg.Key) == "trade_summary" {
+			var sum TradeSummary
+			require.Nil(t, json.Unmarshal(msg.Value, &sum))
+			summaries = append(summaries, sum)
+		}
+	}
+	require.Equal(t, 2, len(summaries))
+	abc := summaries[0]
+	require.Equal(t, "abc/cet", abc.TradingPair)
+	require.Equal(t, int64(7), abc.Height)
+	require.Equal(t, sdk.MustNewDecFromStr("1.5"), abc.Open)
+	require.Equal(t, sdk.MustNewDecFromStr("2"), abc.High)
+	require.Equal(t, sdk.MustNewDecFromStr("1"), abc.Low)
+	require.Equal(t, sdk.MustNewDecFromStr("1"), abc.Close)
+	require.Equal(t, sdk.NewInt(40), abc.StockVolume)
+	require.Equal(t, sdk.NewInt(55), abc.MoneyVolume)
+	require.Equal(t, 3, abc.TradeCount)
+	require.Equal(t, "xyz/cet", summaries[1].TradingPair)
+	require.Equal(t, 1, summaries[1].TradeCount)
+}
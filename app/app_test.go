@@ -30,6 +30,7 @@ import (
 	"github.com/coinexchain/cet-sdk/modules/bankx"
 	types2 "github.com/coinexchain/cet-sdk/modules/distributionx/types"
 	"github.com/coinexchain/cet-sdk/modules/incentive"
+	"github.com/coinexchain/cet-sdk/modules/market"
 	"github.com/coinexchain/cet-sdk/modules/stakingx"
 	"github.com/coinexchain/cet-sdk/msgqueue"
 	"github.com/coinexchain/cet-sdk/testutil"
@@ -795,6 +796,37 @@ func TestCheckTxWithMsgHandle(t *testing.T) {
 	require.Equal(t, bankx.CodeInsufficientCETForActivatingFee, result.Code)
 
 }
+
+func TestCheckTxOrderOfUnknownMarket(t *testing.T) {
+	key, _, addr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(30e8)}
+	app := initAppWithAccounts(acc0)
+
+	header := abci.Header{Height: 1, ChainID: testChainID}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+	app.EndBlock(abci.RequestEndBlock{Height: 1})
+	app.Commit()
+
+	msgOrder := market.MsgCreateOrder{
+		Sender:         addr,
+		TradingPair:    "abc" + market.SymbolSeparator + dex.CET,
+		OrderType:      market.LimitOrder,
+		PricePrecision: 8,
+		Price:          100,
+		Quantity:       10000000,
+		Side:           market.BUY,
+		TimeInForce:    market.GTE,
+	}
+	tx := newStdTxBuilder().
+		Msgs(msgOrder).GasAndFee(1000000, 100).AccNumSeqKey(0, 0, key).Build()
+	require.Equal(t, CodeMarketNotTradable, app.Check(tx).Code)
+
+	// rejected by the market handler in DeliverTx
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 2, ChainID: testChainID}})
+	result := app.Deliver(tx)
+	require.False(t, result.IsOK())
+	require.NotEqual(t, CodeMarketNotTradable, result.Code)
+}
 func TestMsgSetRefereeHandle(t *testing.T) {
 	key, _, senderAddr := testutil.KeyPubAddr()
 	_, _, refereeAddr := testutil.KeyPubAddr()
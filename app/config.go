@@ -0,0 +1,106 @@
+package app
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/spf13/viper"
+)
+
+// EnvUnconfirmedTxLimitTime overrides unconfirmed-tx-limit-time, it is kept for
+// the nodes configured before the setting was moved to app.toml
+const EnvUnconfirmedTxLimitTime = "COINEX_UNCONFIRMED_TX_LIMIT_TIME"
+
+const dexConfigHeader = "##### dex config options #####"
+
+const dexConfigTemplate = `
+` + dexConfigHeader + `
+
+# Where the msgqueue messages are sent, e.g. "kafka:127.0.0.1:9092", "file:/tmp/msgs.txt"
+# or "dir:/tmp/msgs". A "prune:<dir>" entry starts the embedded trade-server, which
+# reads config/trade-server.toml and serves the websocket gateway.
+brokers = [{{ range $i, $b := .Brokers }}{{ if $i }}, {{ end }}"{{ $b }}"{{ end }}]
+
+# Comma separated modules whose messages are sent, e.g. "auth,bank,market,statements,trade_summary"
+subscribe-modules = "{{ .SubscribeModules }}"
+
+# Turns msgqueue on
+feature-toggle = {{ .FeatureToggle }}
+
+# Adds a digest of all the messages of a block to its "commit" message, so that consumers
+# can compare what they received with other nodes subscribing the same modules
+msgqueue-digest = {{ .MsgQueueDigest }}
+
+# CheckTx accepts only one unconfirmed tx per account within this many seconds,
+# a non-positive value turns the limit off
+unconfirmed-tx-limit-time = {{ .UnconfirmedTxLimitTime }}
+
+# Serves the "store-stats" query, which iterates whole stores. Only turn it on for
+# nodes whose RPC is not reachable by the public
+enable-store-stats = {{ .EnableStoreStats }}
+`
+
+var dexTemplate = template.Must(template.New("dexConfigFileTemplate").Parse(dexConfigTemplate))
+
+// DexConfig holds the settings of cetd beyond the SDK's ones. They are read from
+// the same app.toml as top-level keys, where the existing app.toml files of the
+// nodes already have them.
+type DexConfig struct {
+	Brokers                []string `mapstructure:"brokers"`
+	SubscribeModules       string   `mapstructure:"subscribe-modules"`
+	FeatureToggle          bool     `mapstructure:"feature-toggle"`
+	MsgQueueDigest         bool     `mapstructure:"msgqueue-digest"`
+	UnconfirmedTxLimitTime int64    `mapstructure:"unconfirmed-tx-limit-time"`
+	EnableStoreStats       bool     `mapstructure:"enable-store-stats"`
+}
+
+func DefaultDexConfig() DexConfig {
+	return DexConfig{
+		Brokers:                []string{},
+		UnconfirmedTxLimitTime: DefaultLimitTime,
+	}
+}
+
+// LoadDexConfig reads DexConfig from viper, the missing settings keep their defaults
+func LoadDexConfig() (DexConfig, error) {
+	conf := DefaultDexConfig()
+	if err := viper.Unmarshal(&conf); err != nil {
+		return conf, err
+	}
+	if val, ok := os.LookupEnv(EnvUnconfirmedTxLimitTime); ok {
+		limitTime, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			limitTime = -1
+		}
+		conf.UnconfirmedTxLimitTime = limitTime
+	}
+	return conf, nil
+}
+
+// AppendDexConfig appends the dex section to the app.toml at configFilePath,
+// unless the file has it already
+func AppendDexConfig(configFilePath string, conf DexConfig) error {
+	content, err := ioutil.ReadFile(configFilePath)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if strings.Contains(string(content), dexConfigHeader) {
+		return nil
+	}
+
+	var buffer bytes.Buffer
+	if err = dexTemplate.Execute(&buffer, conf); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(configFilePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(buffer.Bytes())
+	return err
+}
@@ -0,0 +1,64 @@
+package app
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadDexConfig(t *testing.T) {
+	defer viper.Reset()
+
+	conf, err := LoadDexConfig()
+	require.Nil(t, err)
+	require.Equal(t, DefaultDexConfig(), conf)
+
+	viper.Set("brokers", []string{"nop"})
+	viper.Set("feature-toggle", true)
+	viper.Set("unconfirmed-tx-limit-time", 30)
+	conf, err = LoadDexConfig()
+	require.Nil(t, err)
+	require.Equal(t, []string{"nop"}, conf.Brokers)
+	require.True(t, conf.FeatureToggle)
+	require.Equal(t, int64(30), conf.UnconfirmedTxLimitTime)
+
+	require.Nil(t, os.Setenv(EnvUnconfirmedTxLimitTime, "0"))
+	defer os.Unsetenv(EnvUnconfirmedTxLimitTime)
+	conf, err = LoadDexConfig()
+	require.Nil(t, err)
+	require.Equal(t, int64(0), conf.UnconfirmedTxLimitTime)
+}
+
+func TestAppendDexConfig(t *testing.T) {
+	defer viper.Reset()
+
+	dir, err := ioutil.TempDir("", "dexconfig")
+	require.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "app.toml")
+	require.Nil(t, ioutil.WriteFile(path, []byte("minimum-gas-prices = \"20cet\"\n"), 0644))
+
+	conf := DefaultDexConfig()
+	conf.Brokers = []string{"kafka:127.0.0.1:9092", "prune:/tmp/ts"}
+	conf.SubscribeModules = "market,statements"
+	conf.MsgQueueDigest = true
+	conf.EnableStoreStats = true
+	require.Nil(t, AppendDexConfig(path, conf))
+	require.Nil(t, AppendDexConfig(path, DefaultDexConfig()))
+
+	content, err := ioutil.ReadFile(path)
+	require.Nil(t, err)
+	require.Equal(t, 1, strings.Count(string(content), dexConfigHeader))
+
+	viper.SetConfigFile(path)
+	require.Nil(t, viper.ReadInConfig())
+	require.Equal(t, "20cet", viper.GetString("minimum-gas-prices"))
+	loaded, err := LoadDexConfig()
+	require.Nil(t, err)
+	require.Equal(t, conf, loaded)
+}
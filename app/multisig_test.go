@@ -0,0 +1,104 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/crypto"
+	"github.com/tendermint/tendermint/crypto/multisig"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// same steps as `cetcli tx sign --multisig` and `cetcli tx multisign`
+func buildMultisigTx(msg sdk.Msg, accNum, seq uint64, multiPub multisig.PubKeyMultisigThreshold, keys ...crypto.PrivKey) auth.StdTx {
+	fee := auth.NewStdFee(9000000, dex.NewCetCoins(100))
+	signBytes := auth.StdSignBytes(testChainID, accNum, seq, fee, []sdk.Msg{msg}, "")
+
+	multiSig := multisig.NewMultisig(len(multiPub.PubKeys))
+	for _, key := range keys {
+		sig, err := key.Sign(signBytes)
+		if err != nil {
+			panic(err)
+		}
+		if err := multiSig.AddSignatureFromPubKey(sig, key.PubKey(), multiPub.PubKeys); err != nil {
+			panic(err)
+		}
+	}
+
+	stdSig := auth.StdSignature{PubKey: multiPub, Signature: multiSig.Marshal()}
+	return auth.NewStdTx([]sdk.Msg{msg}, fee, []auth.StdSignature{stdSig}, "")
+}
+
+func newMultisigAccount(threshold int, n int) ([]crypto.PrivKey, multisig.PubKeyMultisigThreshold) {
+	keys := make([]crypto.PrivKey, n)
+	pubKeys := make([]crypto.PubKey, n)
+	for i := 0; i < n; i++ {
+		keys[i], pubKeys[i], _ = testutil.KeyPubAddr()
+	}
+	multiPub := multisig.NewPubKeyMultisigThreshold(threshold, pubKeys).(multisig.PubKeyMultisigThreshold)
+	return keys, multiPub
+}
+
+func TestMultisigMarketMsgs(t *testing.T) {
+	keys, multiPub := newMultisigAccount(2, 3)
+	addr := sdk.AccAddress(multiPub.Address())
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1e16)}
+
+	app := initAppWithAccounts(acc)
+	header := abci.Header{Height: 1}
+	app.BeginBlock(abci.RequestBeginBlock{Header: header})
+
+	stock, money := "msig000", "cet"
+	msgIssue := asset.NewMsgIssueToken(stock, stock, sdk.NewInt(1e16), addr,
+		false, false, false, false, "", "", asset.TestIdentityString)
+	res := app.Deliver(buildMultisigTx(msgIssue, 0, 0, multiPub, keys[0], keys[1]))
+	require.Equal(t, sdk.CodeOK, res.Code)
+
+	msgPair := market.MsgCreateTradingPair{Stock: stock, Money: money, Creator: addr, PricePrecision: 8}
+	res = app.Deliver(buildMultisigTx(msgPair, 0, 1, multiPub, keys[1], keys[2]))
+	require.Equal(t, sdk.CodeOK, res.Code)
+
+	msgOrder := market.MsgCreateOrder{
+		Sender:         addr,
+		TradingPair:    stock + market.SymbolSeparator + money,
+		OrderType:      market.LimitOrder,
+		PricePrecision: 8,
+		Price:          100,
+		Quantity:       10000000,
+		Side:           market.SELL,
+		TimeInForce:    market.GTE,
+	}
+	res = app.Deliver(buildMultisigTx(msgOrder, 0, 2, multiPub, keys[0], keys[2]))
+	require.Equal(t, sdk.CodeOK, res.Code)
+
+	ctx := app.NewContext(false, header)
+	orders := app.marketKeeper.GetAllOrders(ctx)
+	require.Equal(t, 1, len(orders))
+
+	msgCancel := market.MsgCancelOrder{Sender: addr, OrderID: orders[0].OrderID()}
+	res = app.Deliver(buildMultisigTx(msgCancel, 0, 3, multiPub, keys[0], keys[1], keys[2]))
+	require.Equal(t, sdk.CodeOK, res.Code)
+	require.Equal(t, 0, len(app.marketKeeper.GetAllOrders(ctx)))
+}
+
+func TestMultisigBelowThreshold(t *testing.T) {
+	keys, multiPub := newMultisigAccount(2, 3)
+	addr := sdk.AccAddress(multiPub.Address())
+	acc := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1e16)}
+
+	app := initAppWithAccounts(acc)
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1}})
+
+	msgIssue := asset.NewMsgIssueToken("msig000", "msig000", sdk.NewInt(1e16), addr,
+		false, false, false, false, "", "", asset.TestIdentityString)
+	res := app.Deliver(buildMultisigTx(msgIssue, 0, 0, multiPub, keys[0]))
+	require.Equal(t, sdk.CodeUnauthorized, res.Code)
+}
@@ -1,16 +1,59 @@
 package app
 
 import (
+	"crypto/sha256"
+	"encoding/binary"
+
 	abci "github.com/tendermint/tendermint/abci/types"
+	cmn "github.com/tendermint/tendermint/libs/common"
 
 	"github.com/coinexchain/cet-sdk/msgqueue"
 )
 
+// MsgQueueSchemaVersion is the version of the messages built by the app, such as
+// height_info and notify_tx. Within a major version fields are only added, never
+// removed, renamed or retyped, so consumers must ignore the fields they don't know.
+// The messages of the modules follow the versions of cet-sdk. The version is also
+// carried by "height_info" and "commit", so a consumer attaching at any block learns
+// it from the first block it reads.
+const MsgQueueSchemaVersion = "1.0"
+
+// SchemaVersionInfo is sent as "schema_version" before the messages of the first
+// block a node commits after it starts. It is not counted in the digest of the block.
+type SchemaVersionInfo struct {
+	SchemaVersion string `json:"schema_version"`
+	NodeVersion   string `json:"node_version"`
+	Height        int64  `json:"height"`
+}
+
 type PubMsg struct {
 	Key   []byte
 	Value []byte
 }
 
+// CommitInfo is sent as "commit" after the messages of a block, its Height and
+// MsgsDigest are only filled when msgqueue-digest is on
+type CommitInfo struct {
+	SchemaVersion string       `json:"schema_version"`
+	Height        int64        `json:"height,omitempty"`
+	MsgsDigest    cmn.HexBytes `json:"msgs_digest,omitempty"`
+}
+
+// digestPubMsgs hashes the length-prefixed keys and values of msgs in order
+func digestPubMsgs(msgs []PubMsg) []byte {
+	h := sha256.New()
+	var lenBuf [8]byte
+	for _, msg := range msgs {
+		binary.BigEndian.PutUint64(lenBuf[:], uint64(len(msg.Key)))
+		h.Write(lenBuf[:])
+		h.Write(msg.Key)
+		binary.BigEndian.PutUint64(lenBuf[:], uint64(len(msg.Value)))
+		h.Write(lenBuf[:])
+		h.Write(msg.Value)
+	}
+	return h.Sum(nil)
+}
+
 func collectKafkaEvents(events []abci.Event, app *CetChainApp) []abci.Event {
 	nonKafkaEvents := make([]abci.Event, 0, len(events)) // TODO: no need to make new slice
 	for _, event := range events {
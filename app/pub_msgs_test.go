@@ -1,6 +1,9 @@
 package app
 
 import (
+	"encoding/json"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -52,3 +55,75 @@ func TestDiscardKafkaEvents(t *testing.T) {
 	require.Equal(t, "other", events[0].Type)
 	require.Equal(t, "other", events[1].Type)
 }
+
+func TestDigestPubMsgs(t *testing.T) {
+	msgs1 := []PubMsg{{Key: []byte("k1"), Value: []byte("v1")}, {Key: []byte("k2"), Value: []byte("v2")}}
+	msgs2 := []PubMsg{{Key: []byte("k1v"), Value: []byte("1")}, {Key: []byte("k2"), Value: []byte("v2")}}
+	msgs3 := []PubMsg{{Key: []byte("k2"), Value: []byte("v2")}, {Key: []byte("k1"), Value: []byte("v1")}}
+
+	require.Equal(t, 32, len(digestPubMsgs(nil)))
+	require.Equal(t, digestPubMsgs(msgs1), digestPubMsgs(msgs1))
+	require.NotEqual(t, digestPubMsgs(msgs1), digestPubMsgs(msgs2))
+	require.NotEqual(t, digestPubMsgs(msgs1), digestPubMsgs(msgs3))
+}
+
+func TestGetCommitMsg(t *testing.T) {
+	fakeApp := &CetChainApp{height: 9}
+	fakeApp.appendPubMsgKV("k1", []byte("v1"))
+	require.Equal(t, `{"schema_version":"`+MsgQueueSchemaVersion+`"}`, string(fakeApp.getCommitMsg()))
+
+	fakeApp.enableMsgDigest = true
+	var info CommitInfo
+	require.Nil(t, json.Unmarshal(fakeApp.getCommitMsg(), &info))
+	require.Equal(t, MsgQueueSchemaVersion, info.SchemaVersion)
+	require.Equal(t, int64(9), info.Height)
+	require.Equal(t, digestPubMsgs(fakeApp.pubMsgs), []byte(info.MsgsDigest))
+}
+
+func TestGetSchemaVersionMsg(t *testing.T) {
+	fakeApp := &CetChainApp{height: 9}
+	var info SchemaVersionInfo
+	require.Nil(t, json.Unmarshal(fakeApp.getSchemaVersionMsg(), &info))
+	require.Equal(t, MsgQueueSchemaVersion, info.SchemaVersion)
+	require.Equal(t, int64(9), info.Height)
+}
+
+// schemaV1Fields are the fields of the app's messages in schema 1.x, which must not
+// be removed or renamed before the major version changes
+var schemaV1Fields = []struct {
+	msg    interface{}
+	fields []string
+}{
+	{SchemaVersionInfo{}, []string{"schema_version", "node_version", "height"}},
+	{CommitInfo{}, []string{"schema_version", "height", "msgs_digest"}},
+	{NewHeightInfo{}, []string{"schema_version", "chain_id", "height", "timestamp", "last_block_hash", "orders_created",
+		"orders_filled", "orders_canceled", "tokens_issued", "fees_collected"}},
+	{NotificationTx{}, []string{"signers", "transfers", "serial_number", "msg_types", "tx_json",
+		"height", "hash", "extra_info"}},
+	{TransferRecord{}, []string{"sender", "recipient", "amount"}},
+	{TxExtraInfo{}, []string{"code", "data", "log", "info", "gas_wanted", "gas_used",
+		"events", "codespace"}},
+	{NotificationBeginRedelegation{}, []string{"delegator", "src", "dst", "amount", "completion_time"}},
+	{NotificationBeginUnbonding{}, []string{"delegator", "validator", "amount", "completion_time"}},
+	{NotificationCompleteRedelegation{}, []string{"delegator", "src", "dst"}},
+	{NotificationCompleteUnbonding{}, []string{"delegator", "validator"}},
+	{NotificationSlash{}, []string{"validator", "power", "reason", "jailed"}},
+	{NotificationValidatorCommission{}, []string{"validator", "commission"}},
+	{NotificationDelegatorRewards{}, []string{"validator", "rewards"}},
+	{TradeSummary{}, []string{"trading_pair", "height", "open", "high", "low", "close",
+		"stock_volume", "money_volume", "trade_count"}},
+	{AccountStatement{}, []string{"address", "date", "height", "opening", "closing", "total_in", "total_out"}},
+}
+
+func TestSchemaV1Fields(t *testing.T) {
+	for _, schema := range schemaV1Fields {
+		typ := reflect.TypeOf(schema.msg)
+		tags := make(map[string]bool)
+		for i := 0; i < typ.NumField(); i++ {
+			tags[strings.Split(typ.Field(i).Tag.Get("json"), ",")[0]] = true
+		}
+		for _, field := range schema.fields {
+			require.True(t, tags[field], "%s.%s", typ.Name(), field)
+		}
+	}
+}
@@ -0,0 +1,393 @@
+package app
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"sort"
+
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	distr "github.com/cosmos/cosmos-sdk/x/distribution"
+	"github.com/cosmos/cosmos-sdk/x/staking"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/incentive"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+// app-level queries, which combine the states of several modules
+const (
+	QuerierRoute = "app"
+
+	QueryModuleAccounts = "module-accounts"
+	QuerySupplyInfo     = "supply-info"
+	QueryPortfolio      = "portfolio"
+	QueryStoreStats     = "store-stats"
+)
+
+const (
+	incentivePoolName = "incentive_pool"
+
+	// the endpoints of market's querier
+	marketQueryOrder      = "order-info"
+	marketQueryUserOrders = "user-order-list"
+
+	// the incentive plans are laid out for one block every 3 seconds
+	blocksPerYear = 365 * 24 * 3600 / 3
+)
+
+type ModuleAccountInfo struct {
+	Name        string         `json:"name"`
+	Address     sdk.AccAddress `json:"address"`
+	Permissions []string       `json:"permissions"`
+	Coins       sdk.Coins      `json:"coins"`
+}
+
+func newQuerier(app *CetChainApp) sdk.Querier {
+	return func(ctx sdk.Context, path []string, req abci.RequestQuery) ([]byte, sdk.Error) {
+		switch path[0] {
+		case QueryModuleAccounts:
+			return app.queryModuleAccounts(ctx)
+		case QuerySupplyInfo:
+			return app.querySupplyInfo(ctx)
+		case QueryPortfolio:
+			return app.queryPortfolio(ctx, req)
+		case QueryStoreStats:
+			return app.queryStoreStats(ctx, req)
+		default:
+			return nil, sdk.ErrUnknownRequest("unknown app query endpoint")
+		}
+	}
+}
+
+func (app *CetChainApp) queryModuleAccounts(ctx sdk.Context) ([]byte, sdk.Error) {
+	names := make([]string, 0, len(MaccPerms))
+	for name := range MaccPerms {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	infos := make([]ModuleAccountInfo, 0, len(names)+1)
+	for _, name := range names {
+		infos = append(infos, app.getModuleAccountInfo(ctx, name, supply.NewModuleAddress(name), MaccPerms[name]))
+	}
+	// the incentive pool is a plain account, not a module account of supply
+	infos = append(infos, app.getModuleAccountInfo(ctx, incentivePoolName, incentive.PoolAddr, nil))
+
+	return marshalQueryResult(app.cdc, infos)
+}
+
+func (app *CetChainApp) getModuleAccountInfo(ctx sdk.Context, name string, addr sdk.AccAddress, perms []string) ModuleAccountInfo {
+	coins := sdk.Coins{}
+	if acc := app.accountKeeper.GetAccount(ctx, addr); acc != nil {
+		coins = acc.GetCoins()
+	}
+	return ModuleAccountInfo{
+		Name:        name,
+		Address:     addr,
+		Permissions: perms,
+		Coins:       coins,
+	}
+}
+
+// SupplyInfo summarizes the supply of CET. IncentiveScheduled is what the incentive plans
+// scheduled up to the current height, the payouts stop earlier if the incentive pool runs dry.
+// On a chain restarted from an exported genesis it includes the heights of the former
+// chains, which the incentive module counts through its HeightAdjustment.
+// The incentives are paid from the pre-funded pool and mint no CET, IncentivePayoutRate
+// is the current payout per year relative to TotalSupply. RewardPerBlock and
+// IncentivePayoutRate are zero when the pool cannot pay the next block reward.
+type SupplyInfo struct {
+	GenesisSupply       sdk.Int `json:"genesis_supply"`
+	TotalSupply         sdk.Int `json:"total_supply"`
+	TotalBurned         sdk.Int `json:"total_burned"`
+	TotalMinted         sdk.Int `json:"total_minted"`
+	IncentiveScheduled  sdk.Int `json:"incentive_scheduled"`
+	IncentivePool       sdk.Int `json:"incentive_pool"`
+	RewardPerBlock      int64   `json:"reward_per_block"`
+	IncentivePayoutRate sdk.Dec `json:"incentive_payout_rate"`
+}
+
+func (app *CetChainApp) querySupplyInfo(ctx sdk.Context) ([]byte, sdk.Error) {
+	token := app.tokenKeeper.GetToken(ctx, dex.CET)
+	if token == nil {
+		return nil, sdk.ErrInternal("cet token does not exist")
+	}
+
+	params := app.incentiveKeeper.GetParams(ctx)
+	height := ctx.BlockHeight() + app.incentiveKeeper.GetState(ctx).HeightAdjustment
+
+	info := SupplyInfo{
+		GenesisSupply:       token.GetTotalSupply().Add(token.GetTotalBurn()).Sub(token.GetTotalMint()),
+		TotalSupply:         app.supplyKeeper.GetSupply(ctx).GetTotal().AmountOf(dex.CET),
+		TotalBurned:         token.GetTotalBurn(),
+		TotalMinted:         token.GetTotalMint(),
+		IncentiveScheduled:  sdk.NewInt(scheduledIncentive(params, 0, height)),
+		IncentivePool:       sdk.ZeroInt(),
+		RewardPerBlock:      scheduledIncentive(params, height, height+1),
+		IncentivePayoutRate: sdk.ZeroDec(),
+	}
+	if acc := app.accountKeeper.GetAccount(ctx, incentive.PoolAddr); acc != nil {
+		info.IncentivePool = acc.GetCoins().AmountOf(dex.CET)
+	}
+	// the incentive module skips the payout of a block the pool cannot afford
+	if info.IncentivePool.LT(sdk.NewInt(info.RewardPerBlock)) {
+		info.RewardPerBlock = 0
+	}
+	if info.TotalSupply.IsPositive() {
+		info.IncentivePayoutRate = sdk.NewDec(info.RewardPerBlock).MulInt64(blocksPerYear).QuoInt(info.TotalSupply)
+	}
+	return marshalQueryResult(app.cdc, info)
+}
+
+type QueryPortfolioParams struct {
+	Address sdk.AccAddress `json:"address"`
+}
+
+// Portfolio collects what an address holds across the modules. OrderFrozen is the part
+// of Frozen held by the open orders, Rewards are the pending delegation rewards.
+type Portfolio struct {
+	Address     sdk.AccAddress                `json:"address"`
+	Available   sdk.Coins                     `json:"available"`
+	Locked      authx.LockedCoins             `json:"locked"`
+	Frozen      sdk.Coins                     `json:"frozen"`
+	OrderFrozen sdk.Coins                     `json:"order_frozen"`
+	OpenOrders  int                           `json:"open_orders"`
+	Delegations []staking.DelegationResponse  `json:"delegations"`
+	Unbonding   []staking.UnbondingDelegation `json:"unbonding"`
+	Rewards     sdk.DecCoins                  `json:"rewards"`
+}
+
+func (app *CetChainApp) queryPortfolio(ctx sdk.Context, req abci.RequestQuery) ([]byte, sdk.Error) {
+	var params QueryPortfolioParams
+	if err := app.cdc.UnmarshalJSON(req.Data, &params); err != nil {
+		return nil, sdk.ErrUnknownRequest(sdk.AppendMsgToErr("incorrectly formatted request data", err.Error()))
+	}
+	addr := params.Address
+
+	portfolio := Portfolio{
+		Address:     addr,
+		Available:   sdk.Coins{},
+		Locked:      authx.LockedCoins{},
+		Frozen:      sdk.Coins{},
+		OrderFrozen: sdk.Coins{},
+		Delegations: []staking.DelegationResponse{},
+		Unbonding:   app.stakingKeeper.GetAllUnbondingDelegations(ctx, addr),
+		Rewards:     sdk.DecCoins{},
+	}
+	if acc := app.accountKeeper.GetAccount(ctx, addr); acc != nil {
+		portfolio.Available = acc.GetCoins()
+	}
+	if accx, ok := app.accountXKeeper.GetAccountX(ctx, addr); ok {
+		portfolio.Locked = accx.GetAllLockedCoins()
+		portfolio.Frozen = accx.FrozenCoins
+	}
+
+	orders, err := app.queryUserOrders(ctx, addr)
+	if err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		portfolio.OpenOrders++
+		portfolio.OrderFrozen = portfolio.OrderFrozen.Add(sdk.NewCoins(
+			sdk.NewInt64Coin(order.GetOrderUsedDenom(), order.Freeze),
+			dex.NewCetCoin(order.FrozenCommission+order.FrozenFeatureFee)))
+	}
+
+	for _, del := range app.stakingKeeper.GetAllDelegatorDelegations(ctx, addr) {
+		val, found := app.stakingKeeper.GetValidator(ctx, del.ValidatorAddress)
+		if !found {
+			continue
+		}
+		portfolio.Delegations = append(portfolio.Delegations, staking.NewDelegationResp(
+			del.DelegatorAddress, del.ValidatorAddress, del.Shares, val.TokensFromShares(del.Shares).TruncateInt()))
+	}
+	if len(portfolio.Delegations) != 0 {
+		rewards, err := app.queryDelegatorRewards(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		portfolio.Rewards = rewards
+	}
+	return marshalQueryResult(app.cdc, portfolio)
+}
+
+// the params of market's queries, whose types are internal to market
+type (
+	marketQueryUserOrderList struct{ User string }
+	marketQueryOrderParam    struct{ OrderID string }
+)
+
+// queryUserOrders reuses the querier of market, which reads the order ids of a user
+// from its index of the users' orders
+func (app *CetChainApp) queryUserOrders(ctx sdk.Context, addr sdk.AccAddress) ([]market.Order, sdk.Error) {
+	querier := market.NewAppModule(app.marketKeeper).NewQuerierHandler()
+	req := abci.RequestQuery{Data: app.cdc.MustMarshalJSON(marketQueryUserOrderList{User: addr.String()})}
+	bz, err := querier(ctx, []string{marketQueryUserOrders}, req)
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	if err := app.cdc.UnmarshalJSON(bz, &ids); err != nil {
+		return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not unmarshal order ids", err.Error()))
+	}
+
+	orders := make([]market.Order, 0, len(ids))
+	for _, id := range ids {
+		// an empty list is returned as [""]
+		if id == "" {
+			continue
+		}
+		req = abci.RequestQuery{Data: app.cdc.MustMarshalJSON(marketQueryOrderParam{OrderID: id})}
+		if bz, err = querier(ctx, []string{marketQueryOrder}, req); err != nil {
+			return nil, err
+		}
+		var order market.Order
+		if err := app.cdc.UnmarshalJSON(bz, &order); err != nil {
+			return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not unmarshal order", err.Error()))
+		}
+		orders = append(orders, order)
+	}
+	return orders, nil
+}
+
+// queryDelegatorRewards reuses the querier of distr, because the reward calculation
+// of its keeper is not exported
+func (app *CetChainApp) queryDelegatorRewards(ctx sdk.Context, addr sdk.AccAddress) (sdk.DecCoins, sdk.Error) {
+	req := abci.RequestQuery{Data: app.cdc.MustMarshalJSON(distr.NewQueryDelegatorParams(addr))}
+	bz, err := distr.NewQuerier(app.distrKeeper)(ctx, []string{distr.QueryDelegatorTotalRewards}, req)
+	if err != nil {
+		return nil, err
+	}
+	var res distr.QueryDelegatorTotalRewardsResponse
+	if err := json.Unmarshal(bz, &res); err != nil {
+		return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not unmarshal rewards", err.Error()))
+	}
+	return res.Total, nil
+}
+
+// QueryStoreStatsParams selects the store to be counted, all the stores are counted
+// when Store is empty
+type QueryStoreStatsParams struct {
+	Store string `json:"store"`
+}
+
+// StoreStats counts the entries of a store and the bytes of their keys and values,
+// grouped by the first byte of the keys, which all the modules use as the prefix of
+// the kind of their entries
+type StoreStats struct {
+	Store    string             `json:"store"`
+	Entries  int64              `json:"entries"`
+	Bytes    int64              `json:"bytes"`
+	Prefixes []StorePrefixStats `json:"prefixes"`
+}
+
+type StorePrefixStats struct {
+	Prefix  string `json:"prefix"`
+	Entries int64  `json:"entries"`
+	Bytes   int64  `json:"bytes"`
+}
+
+// queryStoreStats iterates the whole stores, so it is only served when enable-store-stats
+// is turned on in app.toml
+func (app *CetChainApp) queryStoreStats(ctx sdk.Context, req abci.RequestQuery) ([]byte, sdk.Error) {
+	if !app.enableStoreStats {
+		return nil, sdk.ErrUnauthorized("store-stats is disabled, set enable-store-stats in app.toml to turn it on")
+	}
+	var params QueryStoreStatsParams
+	if len(req.Data) != 0 {
+		if err := app.cdc.UnmarshalJSON(req.Data, &params); err != nil {
+			return nil, sdk.ErrUnknownRequest(sdk.AppendMsgToErr("incorrectly formatted request data", err.Error()))
+		}
+	}
+
+	statsList := make([]StoreStats, 0)
+	for _, key := range app.kvStoreKeys() {
+		if params.Store == "" || params.Store == key.Name() {
+			statsList = append(statsList, getStoreStats(ctx, key))
+		}
+	}
+	if len(statsList) == 0 {
+		return nil, sdk.ErrUnknownRequest("unknown store: " + params.Store)
+	}
+	return marshalQueryResult(app.cdc, statsList)
+}
+
+func getStoreStats(ctx sdk.Context, key sdk.StoreKey) StoreStats {
+	stats := StoreStats{Store: key.Name(), Prefixes: []StorePrefixStats{}}
+	iter := ctx.KVStore(key).Iterator(nil, nil)
+	defer iter.Close()
+	for ; iter.Valid(); iter.Next() {
+		size := int64(len(iter.Key()) + len(iter.Value()))
+		stats.Entries++
+		stats.Bytes += size
+
+		// keys are iterated in order, so the entries of a prefix are adjacent
+		prefix := hex.EncodeToString(iter.Key()[:1])
+		last := len(stats.Prefixes) - 1
+		if last < 0 || stats.Prefixes[last].Prefix != prefix {
+			stats.Prefixes = append(stats.Prefixes, StorePrefixStats{Prefix: prefix})
+			last++
+		}
+		stats.Prefixes[last].Entries++
+		stats.Prefixes[last].Bytes += size
+	}
+	return stats
+}
+
+// scheduledIncentive sums the block rewards of the (incentive-adjusted) heights in (from, to],
+// following the same rules as the BeginBlocker of incentive: rewards of all the plans
+// covering a height are added up, and a height covered by no plan gets the default reward.
+func scheduledIncentive(params incentive.Params, from, to int64) int64 {
+	if to <= from {
+		return 0
+	}
+	plans := make([]incentive.Plan, 0, len(params.Plans))
+	total := int64(0)
+	for _, plan := range params.Plans {
+		start, end := maxInt64(plan.StartHeight, from), minInt64(plan.EndHeight, to)
+		if start < end {
+			total += (end - start) * plan.RewardPerBlock
+			plans = append(plans, incentive.Plan{StartHeight: start, EndHeight: end})
+		}
+	}
+	sort.Slice(plans, func(i, j int) bool {
+		return plans[i].StartHeight < plans[j].StartHeight
+	})
+
+	covered, last := int64(0), from
+	for _, plan := range plans {
+		start := maxInt64(plan.StartHeight, last)
+		if start < plan.EndHeight {
+			covered += plan.EndHeight - start
+			last = plan.EndHeight
+		}
+	}
+	return total + (to-from-covered)*params.DefaultRewardPerBlock
+}
+
+func maxInt64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func minInt64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func marshalQueryResult(cdc *codec.Codec, v interface{}) ([]byte, sdk.Error) {
+	bz, err := codec.MarshalJSONIndent(cdc, v)
+	if err != nil {
+		return nil, sdk.ErrInternal(sdk.AppendMsgToErr("could not marshal result to JSON", err.Error()))
+	}
+	return bz, nil
+}
@@ -0,0 +1,206 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/incentive"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestQueryModuleAccounts(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1000)}
+	app := initAppWithAccounts(acc0)
+	ctx := app.NewContext(false, abci.Header{Height: 1})
+
+	bz, err := newQuerier(app)(ctx, []string{QueryModuleAccounts}, abci.RequestQuery{})
+	require.Nil(t, err)
+
+	var infos []ModuleAccountInfo
+	require.Nil(t, app.cdc.UnmarshalJSON(bz, &infos))
+	require.Equal(t, len(MaccPerms)+1, len(infos))
+
+	byName := make(map[string]ModuleAccountInfo, len(infos))
+	for _, info := range infos {
+		byName[info.Name] = info
+	}
+	require.Equal(t, supply.NewModuleAddress(auth.FeeCollectorName), byName[auth.FeeCollectorName].Address)
+	require.Equal(t, []string{supply.Burner, supply.Minter}, byName[asset.ModuleName].Permissions)
+	require.Empty(t, byName[authx.ModuleName].Permissions)
+	require.Equal(t, incentive.PoolAddr, byName[incentivePoolName].Address)
+}
+
+func TestQueryUnknownEndpoint(t *testing.T) {
+	app := initAppWithAccounts()
+	ctx := app.NewContext(false, abci.Header{Height: 1})
+
+	_, err := newQuerier(app)(ctx, []string{"no-such-query"}, abci.RequestQuery{})
+	require.Equal(t, sdk.CodeUnknownRequest, err.Code())
+}
+
+func TestScheduledIncentive(t *testing.T) {
+	params := incentive.Params{
+		DefaultRewardPerBlock: 2,
+		Plans: []incentive.Plan{
+			{StartHeight: 0, EndHeight: 10, RewardPerBlock: 10},
+			{StartHeight: 5, EndHeight: 15, RewardPerBlock: 1},
+			{StartHeight: 20, EndHeight: 30, RewardPerBlock: 5},
+		},
+	}
+
+	tests := []struct {
+		from, to int64
+		want     int64
+	}{
+		{0, 0, 0},
+		{0, 1, 10},
+		{0, 10, 100 + 5},
+		{10, 15, 5},
+		{15, 20, 10},
+		{0, 30, 100 + 10 + 10 + 50},
+		{30, 40, 20},
+		{40, 30, 0},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.want, scheduledIncentive(params, tt.from, tt.to), "(%d, %d]", tt.from, tt.to)
+	}
+}
+
+func TestQuerySupplyInfo(t *testing.T) {
+	app := initAppWithAccounts()
+	ctx := app.NewContext(false, abci.Header{Height: 100})
+
+	bz, err := newQuerier(app)(ctx, []string{QuerySupplyInfo}, abci.RequestQuery{})
+	require.Nil(t, err)
+
+	var info SupplyInfo
+	require.Nil(t, app.cdc.UnmarshalJSON(bz, &info))
+	require.Equal(t, sdk.NewInt(1e18), info.GenesisSupply)
+	require.Equal(t, cetToken().GetTotalSupply(), info.TotalSupply)
+	require.Equal(t, cetToken().GetTotalBurn(), info.TotalBurned)
+	require.Equal(t, sdk.NewInt(100*10e8), info.IncentiveScheduled)
+	// nothing is paid while the pool is dry
+	require.Equal(t, int64(0), info.RewardPerBlock)
+	require.True(t, info.IncentivePayoutRate.IsZero())
+
+	pool := app.accountKeeper.NewAccountWithAddress(ctx, incentive.PoolAddr)
+	require.Nil(t, pool.SetCoins(dex.NewCetCoins(10e8)))
+	app.accountKeeper.SetAccount(ctx, pool)
+	bz, err = newQuerier(app)(ctx, []string{QuerySupplyInfo}, abci.RequestQuery{})
+	require.Nil(t, err)
+	require.Nil(t, app.cdc.UnmarshalJSON(bz, &info))
+	require.Equal(t, sdk.NewInt(10e8), info.IncentivePool)
+	require.Equal(t, int64(10e8), info.RewardPerBlock)
+	require.True(t, info.IncentivePayoutRate.IsPositive())
+
+	// the heights of the former chain are counted on a restarted chain
+	require.Nil(t, app.incentiveKeeper.SetState(ctx, incentive.State{HeightAdjustment: 1000}))
+	bz, err = newQuerier(app)(ctx, []string{QuerySupplyInfo}, abci.RequestQuery{})
+	require.Nil(t, err)
+	require.Nil(t, app.cdc.UnmarshalJSON(bz, &info))
+	require.Equal(t, sdk.NewInt(1100*10e8), info.IncentiveScheduled)
+}
+
+func TestQueryPortfolio(t *testing.T) {
+	key, _, addr := testutil.KeyPubAddr()
+	otherKey, _, otherAddr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1e16)}
+	acc1 := auth.BaseAccount{Address: otherAddr, Coins: dex.NewCetCoins(1e16)}
+	app := initAppWithAccounts(acc0, acc1)
+	app.BeginBlock(abci.RequestBeginBlock{Header: abci.Header{Height: 1}})
+
+	stock := "pfl"
+	newOrder := func(sender sdk.AccAddress, side byte, price int64) market.MsgCreateOrder {
+		return market.MsgCreateOrder{
+			Sender:         sender,
+			TradingPair:    stock + market.SymbolSeparator + dex.CET,
+			OrderType:      market.LimitOrder,
+			PricePrecision: 8,
+			Price:          price,
+			Quantity:       10000000,
+			Side:           side,
+			TimeInForce:    market.GTE,
+		}
+	}
+	msgs := []sdk.Msg{
+		asset.NewMsgIssueToken(stock, stock, sdk.NewInt(1e16), addr,
+			false, false, false, false, "", "", asset.TestIdentityString),
+		market.MsgCreateTradingPair{Stock: stock, Money: dex.CET, Creator: addr, PricePrecision: 8},
+		newOrder(addr, market.SELL, 100),
+	}
+	for seq, msg := range msgs {
+		tx := newStdTxBuilder().
+			Msgs(msg).GasAndFee(9000000, 100).AccNumSeqKey(0, uint64(seq), key).Build()
+		require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+	}
+	tx := newStdTxBuilder().
+		Msgs(newOrder(otherAddr, market.BUY, 50)).GasAndFee(9000000, 100).AccNumSeqKey(1, 0, otherKey).Build()
+	require.Equal(t, sdk.CodeOK, app.Deliver(tx).Code)
+	ctx := app.NewContext(false, abci.Header{Height: 1})
+
+	req := abci.RequestQuery{Data: app.cdc.MustMarshalJSON(QueryPortfolioParams{Address: addr})}
+	bz, err := newQuerier(app)(ctx, []string{QueryPortfolio}, req)
+	require.Nil(t, err)
+
+	var portfolio Portfolio
+	require.Nil(t, app.cdc.UnmarshalJSON(bz, &portfolio))
+	require.Equal(t, addr, portfolio.Address)
+	require.Equal(t, 1, portfolio.OpenOrders)
+	require.Equal(t, int64(10000000), portfolio.OrderFrozen.AmountOf(stock).Int64())
+	require.True(t, portfolio.OrderFrozen.AmountOf(dex.CET).IsPositive())
+	require.Equal(t, portfolio.OrderFrozen, portfolio.Frozen)
+	require.Equal(t, sdk.NewInt(1e16-10000000), portfolio.Available.AmountOf(stock))
+	require.Empty(t, portfolio.Delegations)
+	require.Empty(t, portfolio.Rewards)
+
+	_, err = newQuerier(app)(ctx, []string{QueryPortfolio}, abci.RequestQuery{Data: []byte("{")})
+	require.Equal(t, sdk.CodeUnknownRequest, err.Code())
+}
+
+func TestQueryStoreStats(t *testing.T) {
+	_, _, addr := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: addr, Coins: dex.NewCetCoins(1000)}
+	app := initAppWithAccounts(acc0)
+	ctx := app.NewContext(false, abci.Header{Height: 1})
+
+	_, err := newQuerier(app)(ctx, []string{QueryStoreStats}, abci.RequestQuery{})
+	require.Equal(t, sdk.CodeUnauthorized, err.Code())
+
+	app.enableStoreStats = true
+	bz, err := newQuerier(app)(ctx, []string{QueryStoreStats}, abci.RequestQuery{})
+	require.Nil(t, err)
+	var statsList []StoreStats
+	require.Nil(t, app.cdc.UnmarshalJSON(bz, &statsList))
+	require.Equal(t, len(app.kvStoreKeys()), len(statsList))
+
+	req := abci.RequestQuery{Data: app.cdc.MustMarshalJSON(QueryStoreStatsParams{Store: auth.StoreKey})}
+	bz, err = newQuerier(app)(ctx, []string{QueryStoreStats}, req)
+	require.Nil(t, err)
+	require.Nil(t, app.cdc.UnmarshalJSON(bz, &statsList))
+	require.Equal(t, 1, len(statsList))
+	acc := statsList[0]
+	require.Equal(t, auth.StoreKey, acc.Store)
+	var entries, size int64
+	for _, prefix := range acc.Prefixes {
+		entries += prefix.Entries
+		size += prefix.Bytes
+	}
+	require.True(t, acc.Entries > 1)
+	require.Equal(t, acc.Entries, entries)
+	require.Equal(t, acc.Bytes, size)
+
+	req.Data = app.cdc.MustMarshalJSON(QueryStoreStatsParams{Store: "no-such-store"})
+	_, err = newQuerier(app)(ctx, []string{QueryStoreStats}, req)
+	require.Equal(t, sdk.CodeUnknownRequest, err.Code())
+}
@@ -0,0 +1,47 @@
+package app
+
+import (
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	authexported "github.com/cosmos/cosmos-sdk/x/auth/exported"
+	supplyexported "github.com/cosmos/cosmos-sdk/x/supply/exported"
+)
+
+// TokenHolder is the balance of one account in one token. Locked and Frozen are kept
+// by authx apart from the account's coins, Total is the sum of all three.
+type TokenHolder struct {
+	Address   sdk.AccAddress `json:"address"`
+	Available sdk.Int        `json:"available"`
+	Locked    sdk.Int        `json:"locked"`
+	Frozen    sdk.Int        `json:"frozen"`
+	Total     sdk.Int        `json:"total"`
+}
+
+// GetTokenHolders returns all the accounts holding some denom, ordered by address.
+// Module accounts are skipped, the authx module account only mirrors the locked
+// and frozen coins of the other accounts. Delegated CET is not included.
+func (app *CetChainApp) GetTokenHolders(ctx sdk.Context, denom string) []TokenHolder {
+	var holders []TokenHolder
+	app.accountKeeper.IterateAccounts(ctx, func(acc authexported.Account) bool {
+		if _, ok := acc.(supplyexported.ModuleAccountI); ok {
+			return false
+		}
+		holder := TokenHolder{
+			Address:   acc.GetAddress(),
+			Available: acc.GetCoins().AmountOf(denom),
+			Locked:    sdk.ZeroInt(),
+			Frozen:    sdk.ZeroInt(),
+		}
+		if accx, ok := app.accountXKeeper.GetAccountX(ctx, acc.GetAddress()); ok {
+			for _, locked := range accx.GetLockedCoinsByDemon(denom) {
+				holder.Locked = holder.Locked.Add(locked.Coin.Amount)
+			}
+			holder.Frozen = accx.FrozenCoins.AmountOf(denom)
+		}
+		holder.Total = holder.Available.Add(holder.Locked).Add(holder.Frozen)
+		if holder.Total.IsPositive() {
+			holders = append(holders, holder)
+		}
+		return false
+	})
+	return holders
+}
@@ -0,0 +1,62 @@
+package app
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/supply"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+)
+
+func TestGetTokenHolders(t *testing.T) {
+	_, _, addr0 := testutil.KeyPubAddr()
+	_, _, addr1 := testutil.KeyPubAddr()
+	acc0 := auth.BaseAccount{Address: addr0, Coins: dex.NewCetCoins(1000)}
+	acc1 := auth.BaseAccount{Address: addr1, Coins: sdk.NewCoins(sdk.NewInt64Coin("abc", 5))}
+	app := initAppWithAccounts(acc0, acc1)
+	ctx := app.NewContext(false, abci.Header{Height: 1})
+
+	accx := authx.NewAccountXWithAddress(addr1)
+	accx.AddLockedCoins(authx.LockedCoins{
+		authx.NewLockedCoin(dex.CET, sdk.NewInt(30), 100),
+		authx.NewLockedCoin("abc", sdk.NewInt(7), 100),
+		authx.NewLockedCoin(dex.CET, sdk.NewInt(10), 200),
+	})
+	accx.FrozenCoins = dex.NewCetCoins(20)
+	app.accountXKeeper.SetAccountX(ctx, accx)
+	app.accountXKeeper.PreTotalSupply(ctx)
+
+	holders := make(map[string]TokenHolder)
+	for _, holder := range app.GetTokenHolders(ctx, dex.CET) {
+		holders[holder.Address.String()] = holder
+	}
+	require.Equal(t, TokenHolder{
+		Address:   addr0,
+		Available: sdk.NewInt(1000),
+		Locked:    sdk.ZeroInt(),
+		Frozen:    sdk.ZeroInt(),
+		Total:     sdk.NewInt(1000),
+	}, holders[addr0.String()])
+	require.Equal(t, TokenHolder{
+		Address:   addr1,
+		Available: sdk.ZeroInt(),
+		Locked:    sdk.NewInt(40),
+		Frozen:    sdk.NewInt(20),
+		Total:     sdk.NewInt(60),
+	}, holders[addr1.String()])
+	require.NotContains(t, holders, supply.NewModuleAddress(authx.ModuleName).String())
+
+	holders = make(map[string]TokenHolder)
+	for _, holder := range app.GetTokenHolders(ctx, "abc") {
+		holders[holder.Address.String()] = holder
+	}
+	require.Len(t, holders, 1)
+	require.Equal(t, sdk.NewInt(12), holders[addr1.String()].Total)
+}
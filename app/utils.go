@@ -22,7 +22,7 @@ const (
 	TSDirCfg = "dir"
 )
 
-func initConf() (*toml.Tree, error) {
+func initConf(brokers []string) (*toml.Tree, error) {
 	conf := cfg.DefaultConfig()
 	err := viper.Unmarshal(conf)
 	if err != nil {
@@ -37,18 +37,17 @@ func initConf() (*toml.Tree, error) {
 	if err != nil {
 		return config, err
 	}
-	path := strings.Split(getPreFixBks(msgqueue.CfgPrefixPrune), msgqueue.CfgPrefixPrune)[1]
+	path := strings.Split(getPreFixBks(brokers, msgqueue.CfgPrefixPrune), msgqueue.CfgPrefixPrune)[1]
 	config.Set(TSDirCfg, path)
 	return config, err
 }
 
-func isOpenTs() bool {
-	bkCfg := getPreFixBks(msgqueue.CfgPrefixPrune)
+func isOpenTs(brokers []string) bool {
+	bkCfg := getPreFixBks(brokers, msgqueue.CfgPrefixPrune)
 	return len(bkCfg) > 0
 }
 
-func getPreFixBks(prefix string) string {
-	brokers := viper.GetStringSlice(msgqueue.FlagBrokers)
+func getPreFixBks(brokers []string, prefix string) string {
 	for _, b := range brokers {
 		if strings.HasPrefix(b, prefix) {
 			return b
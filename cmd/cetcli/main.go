@@ -132,6 +132,10 @@ func queryCmd(cdc *codec.Codec) *cobra.Command {
 
 	queryCmd.AddCommand(
 		authxcmd.GetAccountXCmd(cdc),
+		moduleAccountsCmd(cdc),
+		supplyInfoCmd(cdc),
+		portfolioCmd(cdc),
+		storeStatsCmd(cdc),
 		client.LineBreak,
 		rpc.ValidatorCommand(cdc),
 		rpc.BlockCommand(),
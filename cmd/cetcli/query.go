@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/cosmos-utils/client/cliutil"
+	"github.com/coinexchain/dex/app"
+)
+
+func moduleAccountsCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "module-accounts",
+		Short: "Query all module accounts with their permissions and balances",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			route := fmt.Sprintf("custom/%s/%s", app.QuerierRoute, app.QueryModuleAccounts)
+			return cliutil.CliQuery(cdc, route, nil)
+		},
+	}
+	return flags.GetCommands(cmd)[0]
+}
+
+func supplyInfoCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "supply-info",
+		Short: "Query the genesis, current, burned and incentive supply of CET",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			route := fmt.Sprintf("custom/%s/%s", app.QuerierRoute, app.QuerySupplyInfo)
+			return cliutil.CliQuery(cdc, route, nil)
+		},
+	}
+	return flags.GetCommands(cmd)[0]
+}
+
+func portfolioCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "portfolio [address]",
+		Short: "Query the balances, locked and frozen coins, open orders, delegations and rewards of an address",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			addr, err := sdk.AccAddressFromBech32(args[0])
+			if err != nil {
+				return err
+			}
+			route := fmt.Sprintf("custom/%s/%s", app.QuerierRoute, app.QueryPortfolio)
+			return cliutil.CliQuery(cdc, route, app.QueryPortfolioParams{Address: addr})
+		},
+	}
+	return flags.GetCommands(cmd)[0]
+}
+
+func storeStatsCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "store-stats [store]",
+		Short: "Query the entry counts and sizes of the stores, grouped by key prefix",
+		Long: `Query the entry counts and sizes of all the stores, or of the given one (e.g. "market"),
+grouped by the first byte of the keys. All the entries are iterated, so the query can be slow,
+and nodes only serve it when enable-store-stats is turned on in their app.toml.`,
+		Args: cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var params app.QueryStoreStatsParams
+			if len(args) != 0 {
+				params.Store = args[0]
+			}
+			route := fmt.Sprintf("custom/%s/%s", app.QuerierRoute, app.QueryStoreStats)
+			return cliutil.CliQuery(cdc, route, params)
+		},
+	}
+	return flags.GetCommands(cmd)[0]
+}
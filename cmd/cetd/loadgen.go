@@ -0,0 +1,356 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/crypto/secp256k1"
+	rpcclient "github.com/tendermint/tendermint/rpc/client"
+
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/client/keys"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/bank"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/bankx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagLoadAccounts       = "accounts"
+	flagLoadTPS            = "tps"
+	flagLoadDuration       = "duration"
+	flagLoadFund           = "fund"
+	flagLoadTradingPair    = "trading-pair"
+	flagLoadPrice          = "price"
+	flagLoadPricePrecision = "price-precision"
+	flagLoadQuantity       = "quantity"
+	flagLoadSeed           = "seed"
+	flagLoadKeyPass        = "key-pass"
+	flagLoadClientHome     = "home-client"
+	flagLoadFunder         = "funder"
+
+	loadgenGas = 200000
+)
+
+type loadAccount struct {
+	key       secp256k1.PrivKeySecp256k1
+	addr      sdk.AccAddress
+	accNum    uint64
+	seq       uint64
+	lastOrder string
+}
+
+type loadResult struct {
+	latency time.Duration
+	code    uint32
+	err     error
+}
+
+type loadReport struct {
+	sent     int
+	accepted int
+	rejected map[uint32]int
+	failed   int
+	elapsed  time.Duration
+	min      time.Duration
+	avg      time.Duration
+	p50      time.Duration
+	p90      time.Duration
+	p99      time.Duration
+	max      time.Duration
+}
+
+func loadgenCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "loadgen",
+		Short: "Send signed transactions to a running node at a target rate",
+		Long: `loadgen creates a number of accounts, funds them from a local key and then
+lets every account send transactions to the node in turn, so that together they
+reach the target TPS. Without --trading-pair the accounts send 1 sato CET to each
+other, with it they also place buy orders on the pair and cancel them. The money of
+the pair must be CET.
+
+When it finishes, it reports how many transactions passed CheckTx and the latency
+of BroadcastTxSync. Note that by default a node accepts only one unconfirmed tx per
+account, set unconfirmed-tx-limit-time = 0 in its app.toml or use more accounts.
+
+Example:
+	cetd testnet loadgen --home-client ./mytestnet/node0/cetcli --funder node0 --accounts 200 --tps 100 --duration 60s
+	`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			tps := viper.GetFloat64(flagLoadTPS)
+			numAccounts := viper.GetInt(flagLoadAccounts)
+			if tps <= 0 || numAccounts <= 0 {
+				return fmt.Errorf("%s and %s must be positive", flagLoadTPS, flagLoadAccounts)
+			}
+
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			chainID := viper.GetString(flags.FlagChainID)
+			if chainID == "" {
+				status, err := cliCtx.Client.Status()
+				if err != nil {
+					return err
+				}
+				chainID = status.NodeInfo.Network
+			}
+
+			accounts := genLoadAccounts(numAccounts, viper.GetInt64(flagLoadSeed))
+			if err := fundLoadAccounts(cliCtx, chainID, accounts, viper.GetInt64(flagLoadFund)); err != nil {
+				return err
+			}
+			cmd.PrintErrf("Funded %d accounts\n", len(accounts))
+
+			report := runLoad(cdc, cliCtx.Client, chainID, accounts, tps, viper.GetDuration(flagLoadDuration))
+			printLoadReport(cmd, report)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int(flagLoadAccounts, 100, "Number of accounts sending transactions")
+	cmd.Flags().Float64(flagLoadTPS, 50, "Target number of transactions per second")
+	cmd.Flags().Duration(flagLoadDuration, time.Minute, "How long to send transactions")
+	cmd.Flags().Int64(flagLoadFund, 100e8, "Amount of sato CET sent to each account before the test")
+	cmd.Flags().String(flagLoadTradingPair, "", "Place and cancel orders on this trading pair")
+	cmd.Flags().Int64(flagLoadPrice, 1, "Price of the orders, scaled by the price precision")
+	cmd.Flags().Int(flagLoadPricePrecision, 0, "Price precision of the orders")
+	cmd.Flags().Int64(flagLoadQuantity, 1e8, "Quantity of the orders")
+	cmd.Flags().Int64(flagLoadSeed, 0, "Seed from which the accounts' private keys are derived")
+	cmd.Flags().String(flagLoadKeyPass, app.DefaultKeyPass, "Password of the key funding the accounts")
+	cmd.Flags().String(flags.FlagNode, "tcp://localhost:26657", "<host>:<port> to tendermint rpc interface for this chain")
+	cmd.Flags().String(flags.FlagChainID, "", "Chain ID of the node, queried from the node if not set")
+	cmd.Flags().String(flagLoadFunder, "", "Name of the key funding the accounts")
+	cmd.Flags().String(flagLoadClientHome, app.DefaultCLIHome, "Client's home directory with the key funding the accounts")
+	_ = cmd.MarkFlagRequired(flagLoadFunder)
+	return cmd
+}
+
+// the accounts are derived from the seed, so a test can be repeated without funding them again
+func genLoadAccounts(n int, seed int64) []*loadAccount {
+	accounts := make([]*loadAccount, n)
+	for i := range accounts {
+		key := secp256k1.GenPrivKeySecp256k1([]byte(fmt.Sprintf("loadgen-%d-%d", seed, i)))
+		accounts[i] = &loadAccount{key: key, addr: sdk.AccAddress(key.PubKey().Address())}
+	}
+	return accounts
+}
+
+func fundLoadAccounts(cliCtx context.CLIContext, chainID string, accounts []*loadAccount, amount int64) error {
+	kb, err := keys.NewKeyBaseFromDir(viper.GetString(flagLoadClientHome))
+	if err != nil {
+		return err
+	}
+	info, err := kb.Get(viper.GetString(flagLoadFunder))
+	if err != nil {
+		return err
+	}
+	from := info.GetAddress()
+	funder, err := auth.NewAccountRetriever(cliCtx).GetAccount(from)
+	if err != nil {
+		return err
+	}
+
+	coins := dex.NewCetCoins(amount)
+	outputs := make([]bank.Output, len(accounts))
+	for i, acc := range accounts {
+		outputs[i] = bank.NewOutput(acc.addr, coins)
+	}
+	total := dex.NewCetCoins(amount * int64(len(accounts)))
+	msg := bankx.NewMsgMultiSend([]bank.Input{bank.NewInput(from, total)}, outputs)
+
+	gas := uint64(loadgenGas * (1 + len(accounts)/10))
+	txBldr := auth.NewTxBuilder(auth.DefaultTxEncoder(cliCtx.Codec), funder.GetAccountNumber(), funder.GetSequence(),
+		gas, 0, false, chainID, "", nil, loadgenGasPrices()).WithKeybase(kb)
+	txBytes, err := txBldr.BuildAndSign(info.GetName(), viper.GetString(flagLoadKeyPass), []sdk.Msg{msg})
+	if err != nil {
+		return err
+	}
+	res, err := cliCtx.WithBroadcastMode(flags.BroadcastBlock).BroadcastTx(txBytes)
+	if err != nil {
+		return err
+	}
+	if res.Code != uint32(sdk.CodeOK) {
+		return fmt.Errorf("failed to fund the accounts: %s", res.RawLog)
+	}
+
+	for _, acc := range accounts {
+		account, err := auth.NewAccountRetriever(cliCtx).GetAccount(acc.addr)
+		if err != nil {
+			return err
+		}
+		acc.accNum, acc.seq = account.GetAccountNumber(), account.GetSequence()
+	}
+	return nil
+}
+
+func loadgenGasPrices() sdk.DecCoins {
+	return sdk.DecCoins{sdk.NewDecCoinFromDec(dex.CET, sdk.MustNewDecFromStr(authx.DefaultMinGasPriceLimit))}
+}
+
+func loadgenFee(gas uint64) sdk.Coins {
+	return dex.NewCetCoins(loadgenGasPrices()[0].Amount.MulInt64(int64(gas)).Ceil().RoundInt64())
+}
+
+// runLoad lets each account send one tx every len(accounts)/tps seconds. An account
+// waits for the result of its last tx, so a slow node lowers the achieved rate.
+func runLoad(cdc *codec.Codec, client rpcclient.Client, chainID string,
+	accounts []*loadAccount, tps float64, duration time.Duration) loadReport {
+
+	interval := time.Duration(float64(len(accounts)) / tps * float64(time.Second))
+	results := make(chan loadResult, 1024)
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+
+	start := time.Now()
+	for i, acc := range accounts {
+		wg.Add(1)
+		go func(i int, acc *loadAccount) {
+			defer wg.Done()
+			// spread the accounts over the interval instead of sending all at once
+			time.Sleep(interval * time.Duration(i) / time.Duration(len(accounts)))
+			ticker := time.NewTicker(interval)
+			defer ticker.Stop()
+			to := accounts[(i+1)%len(accounts)].addr
+			for n := 0; ; n++ {
+				results <- sendLoadTx(cdc, client, chainID, acc, nextLoadMsg(acc, to, n))
+				select {
+				case <-stop:
+					return
+				case <-ticker.C:
+				}
+			}
+		}(i, acc)
+	}
+	go func() {
+		time.Sleep(duration)
+		close(stop)
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []loadResult
+	for res := range results {
+		all = append(all, res)
+	}
+	return summarizeLoad(all, time.Since(start))
+}
+
+// without a trading pair every tx is a send, with one an account cycles through
+// send, create order and cancel the order it created
+func nextLoadMsg(acc *loadAccount, to sdk.AccAddress, n int) sdk.Msg {
+	pair := viper.GetString(flagLoadTradingPair)
+	if pair == "" || n%3 == 0 || (n%3 == 2 && acc.lastOrder == "") {
+		return bankx.NewMsgSend(acc.addr, to, dex.NewCetCoins(1), 0)
+	}
+	if n%3 == 2 {
+		return market.MsgCancelOrder{Sender: acc.addr, OrderID: acc.lastOrder}
+	}
+
+	// the ante handler increases the sequence before the order is created
+	order := market.Order{Sender: acc.addr, Sequence: acc.seq + 1}
+	acc.lastOrder = order.OrderID()
+	return market.MsgCreateOrder{
+		Sender:         acc.addr,
+		TradingPair:    pair,
+		OrderType:      market.LimitOrder,
+		PricePrecision: byte(viper.GetInt(flagLoadPricePrecision)),
+		Price:          viper.GetInt64(flagLoadPrice),
+		Quantity:       viper.GetInt64(flagLoadQuantity),
+		Side:           market.BUY,
+		TimeInForce:    market.GTE,
+	}
+}
+
+func sendLoadTx(cdc *codec.Codec, client rpcclient.Client, chainID string, acc *loadAccount, msg sdk.Msg) loadResult {
+	msgs := []sdk.Msg{msg}
+	fee := auth.NewStdFee(loadgenGas, loadgenFee(loadgenGas))
+	sig, err := acc.key.Sign(auth.StdSignBytes(chainID, acc.accNum, acc.seq, fee, msgs, ""))
+	if err != nil {
+		return loadResult{err: err}
+	}
+	tx := auth.NewStdTx(msgs, fee, []auth.StdSignature{{PubKey: acc.key.PubKey(), Signature: sig}}, "")
+	txBytes, err := cdc.MarshalBinaryLengthPrefixed(tx)
+	if err != nil {
+		return loadResult{err: err}
+	}
+
+	start := time.Now()
+	res, err := client.BroadcastTxSync(txBytes)
+	latency := time.Since(start)
+	if err != nil {
+		return loadResult{latency: latency, err: err}
+	}
+	if res.Code == uint32(sdk.CodeOK) {
+		acc.seq++
+	} else if res.Code == uint32(sdk.CodeUnauthorized) {
+		// most likely a wrong sequence after an earlier tx failed in DeliverTx
+		acc.seq = queryLoadSequence(cdc, client, acc)
+	}
+	return loadResult{latency: latency, code: res.Code}
+}
+
+func queryLoadSequence(cdc *codec.Codec, client rpcclient.Client, acc *loadAccount) uint64 {
+	cliCtx := context.NewCLIContext().WithCodec(cdc).WithClient(client)
+	account, err := auth.NewAccountRetriever(cliCtx).GetAccount(acc.addr)
+	if err != nil {
+		return acc.seq
+	}
+	return account.GetSequence()
+}
+
+func summarizeLoad(results []loadResult, elapsed time.Duration) loadReport {
+	report := loadReport{sent: len(results), rejected: make(map[uint32]int), elapsed: elapsed}
+	latencies := make([]time.Duration, 0, len(results))
+	total := time.Duration(0)
+	for _, res := range results {
+		switch {
+		case res.err != nil:
+			report.failed++
+			continue
+		case res.code == uint32(sdk.CodeOK):
+			report.accepted++
+		default:
+			report.rejected[res.code]++
+		}
+		latencies = append(latencies, res.latency)
+		total += res.latency
+	}
+	if len(latencies) == 0 {
+		return report
+	}
+
+	sort.Slice(latencies, func(i, j int) bool { return latencies[i] < latencies[j] })
+	percentile := func(p int) time.Duration {
+		return latencies[(len(latencies)-1)*p/100]
+	}
+	report.min, report.max = latencies[0], latencies[len(latencies)-1]
+	report.avg = total / time.Duration(len(latencies))
+	report.p50, report.p90, report.p99 = percentile(50), percentile(90), percentile(99)
+	return report
+}
+
+func printLoadReport(cmd *cobra.Command, r loadReport) {
+	cmd.Printf("sent: %d, accepted: %d, failed to broadcast: %d, elapsed: %s, accepted tps: %.2f\n",
+		r.sent, r.accepted, r.failed, r.elapsed.Round(time.Millisecond), float64(r.accepted)/r.elapsed.Seconds())
+	codes := make([]int, 0, len(r.rejected))
+	for code := range r.rejected {
+		codes = append(codes, int(code))
+	}
+	sort.Ints(codes)
+	for _, code := range codes {
+		cmd.Printf("rejected with code %d: %d\n", code, r.rejected[uint32(code)])
+	}
+	cmd.Printf("latency min: %s, avg: %s, p50: %s, p90: %s, p99: %s, max: %s\n",
+		r.min, r.avg, r.p50, r.p90, r.p99, r.max)
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+)
+
+func TestSummarizeLoad(t *testing.T) {
+	var results []loadResult
+	for i := 1; i <= 100; i++ {
+		results = append(results, loadResult{latency: time.Duration(i) * time.Millisecond})
+	}
+	results = append(results,
+		loadResult{latency: 200 * time.Millisecond, code: uint32(sdk.CodeUnauthorized)},
+		loadResult{err: errors.New("connection refused")},
+	)
+
+	report := summarizeLoad(results, 10*time.Second)
+	require.Equal(t, 102, report.sent)
+	require.Equal(t, 100, report.accepted)
+	require.Equal(t, 1, report.failed)
+	require.Equal(t, map[uint32]int{uint32(sdk.CodeUnauthorized): 1}, report.rejected)
+	require.Equal(t, time.Millisecond, report.min)
+	require.Equal(t, 200*time.Millisecond, report.max)
+	require.Equal(t, 51*time.Millisecond, report.p50)
+	require.Equal(t, 91*time.Millisecond, report.p90)
+
+	report = summarizeLoad(nil, time.Second)
+	require.Equal(t, 0, report.sent)
+	require.Equal(t, time.Duration(0), report.max)
+}
+
+func TestGenLoadAccounts(t *testing.T) {
+	accounts := genLoadAccounts(3, 7)
+	require.Len(t, accounts, 3)
+	require.NotEqual(t, accounts[0].addr, accounts[1].addr)
+	require.Equal(t, accounts[2].addr, genLoadAccounts(3, 7)[2].addr)
+	require.NotEqual(t, accounts[0].addr, genLoadAccounts(1, 8)[0].addr)
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"encoding/json"
 	"io"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -82,6 +83,10 @@ func addInitCommands(ctx *server.Context, cdc *codec.Codec, rootCmd *cobra.Comma
 	initCmd.PreRun = func(cmd *cobra.Command, args []string) {
 		adjustBlockCommitSpeed(ctx.Config)
 	}
+	initCmd.PostRunE = func(cmd *cobra.Command, args []string) error {
+		appConfigFilePath := filepath.Join(ctx.Config.RootDir, "config/app.toml")
+		return app.AppendDexConfig(appConfigFilePath, app.DefaultDexConfig())
+	}
 	rootCmd.AddCommand(initCmd)
 	rootCmd.AddCommand(genutilcli.CollectGenTxsCmd(ctx, cdc, genaccounts.AppModuleBasic{}, app.DefaultNodeHome))
 	rootCmd.AddCommand(genutilcli.GenTxCmd(ctx, cdc, rawBasicManager, staking.AppModuleBasic{},
@@ -91,6 +96,7 @@ func addInitCommands(ctx *server.Context, cdc *codec.Codec, rootCmd *cobra.Comma
 	rootCmd.AddCommand(assetcli.AddGenesisTokenCmd(ctx, cdc, app.DefaultNodeHome, app.DefaultCLIHome))
 	rootCmd.AddCommand(testnetCmd(ctx, cdc, app.ModuleBasics, genaccounts.AppModuleBasic{}))
 	rootCmd.AddCommand(migrateCmd(cdc))
+	rootCmd.AddCommand(snapshotBalancesCmd(ctx, cdc))
 }
 
 func adjustBlockCommitSpeed(config *tmconfig.Config) {
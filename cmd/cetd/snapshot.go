@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/cli"
+
+	"github.com/cosmos/cosmos-sdk/codec"
+	"github.com/cosmos/cosmos-sdk/server"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagSnapshotHeight = "height"
+	flagSnapshotFormat = "format"
+
+	snapshotFormatJSON = "json"
+	snapshotFormatCSV  = "csv"
+)
+
+type balanceSnapshot struct {
+	Height  int64             `json:"height"`
+	Symbol  string            `json:"symbol"`
+	Holders []app.TokenHolder `json:"holders"`
+}
+
+func snapshotBalancesCmd(ctx *server.Context, cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "snapshot-balances [symbol]",
+		Short: "Export the balances of all holders of a token at some height",
+		Long: `snapshot-balances reads the application state of a stopped node and prints the
+available, locked and frozen balances of every account holding the token, e.g. for
+an airdrop. Delegated CET is not included.
+
+Only the heights kept by the node's pruning strategy can be read, start the node
+with --pruning=nothing to snapshot arbitrary heights.
+
+Example:
+	cetd snapshot-balances cet --height 1000000 --format csv > holders.csv
+	`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format := viper.GetString(flagSnapshotFormat)
+			if format != snapshotFormatJSON && format != snapshotFormatCSV {
+				return fmt.Errorf("unknown format: %s", format)
+			}
+
+			dataDir := filepath.Join(viper.GetString(cli.HomeFlag), "data")
+			db, err := sdk.NewLevelDB("application", dataDir)
+			if err != nil {
+				return err
+			}
+			defer db.Close()
+
+			height := viper.GetInt64(flagSnapshotHeight)
+			gApp := app.NewCetChainApp(ctx.Logger, db, nil, height == -1, uint(1))
+			if height != -1 {
+				if err = gApp.LoadHeight(height); err != nil {
+					return err
+				}
+			}
+
+			snapshot := balanceSnapshot{Height: gApp.LastBlockHeight(), Symbol: args[0]}
+			sdkCtx := gApp.NewContext(true, abci.Header{Height: snapshot.Height})
+			snapshot.Holders = gApp.GetTokenHolders(sdkCtx, snapshot.Symbol)
+
+			if format == snapshotFormatCSV {
+				return writeSnapshotCSV(cmd.OutOrStdout(), snapshot)
+			}
+			bz, err := codec.MarshalJSONIndent(cdc, snapshot)
+			if err != nil {
+				return err
+			}
+			cmd.Println(string(bz))
+			return nil
+		},
+	}
+
+	cmd.Flags().Int64(flagSnapshotHeight, -1, "Height of the snapshot (-1 means latest height)")
+	cmd.Flags().String(flagSnapshotFormat, snapshotFormatJSON, "Output format, json or csv")
+	return cmd
+}
+
+func writeSnapshotCSV(out io.Writer, snapshot balanceSnapshot) error {
+	w := csv.NewWriter(out)
+	if err := w.Write([]string{"address", "available", "locked", "frozen", "total"}); err != nil {
+		return err
+	}
+	for _, h := range snapshot.Holders {
+		record := []string{h.Address.String(), h.Available.String(), h.Locked.String(), h.Frozen.String(), h.Total.String()}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
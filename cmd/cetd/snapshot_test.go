@@ -0,0 +1,31 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+
+	"github.com/coinexchain/dex/app"
+)
+
+func TestWriteSnapshotCSV(t *testing.T) {
+	addr := sdk.AccAddress([]byte("addr1_______________"))
+	snapshot := balanceSnapshot{
+		Height: 10,
+		Symbol: "abc",
+		Holders: []app.TokenHolder{{
+			Address:   addr,
+			Available: sdk.NewInt(5),
+			Locked:    sdk.NewInt(3),
+			Frozen:    sdk.NewInt(2),
+			Total:     sdk.NewInt(10),
+		}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, writeSnapshotCSV(&buf, snapshot))
+	require.Equal(t, "address,available,locked,frozen,total\n"+addr.String()+",5,3,2,10\n", buf.String())
+}
@@ -99,6 +99,7 @@ Example:
 	}
 
 	prepareFlagsForTestnetCmd(cmd)
+	cmd.AddCommand(loadgenCmd(cdc))
 
 	return cmd
 }
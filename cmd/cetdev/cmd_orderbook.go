@@ -0,0 +1,382 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"math/rand"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/tendermint/tendermint/crypto"
+	tm "github.com/tendermint/tendermint/types"
+
+	"github.com/cosmos/cosmos-sdk/client/context"
+	"github.com/cosmos/cosmos-sdk/client/flags"
+	"github.com/cosmos/cosmos-sdk/codec"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/authx"
+	"github.com/coinexchain/cet-sdk/modules/market"
+	"github.com/coinexchain/dex/app"
+)
+
+const (
+	flagDepth          = "depth"
+	flagTraders        = "traders"
+	flagMidPrice       = "mid-price"
+	flagPriceStep      = "price-step"
+	flagMinQuantity    = "min-quantity"
+	flagMaxQuantity    = "max-quantity"
+	flagDistribution   = "distribution"
+	flagSeed           = "seed"
+	flagPricePrecision = "price-precision"
+	flagImport         = "import"
+	flagOutFile        = "out-file"
+
+	distributionFlat    = "flat"
+	distributionRandom  = "random"
+	distributionPyramid = "pyramid"
+)
+
+type orderBookConfig struct {
+	depth        int
+	traders      int
+	midPrice     sdk.Dec
+	priceStep    sdk.Dec
+	minQuantity  int64
+	maxQuantity  int64
+	distribution string
+	seed         int64
+}
+
+func GenOrderBookCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "gen-orderbook [genesis-file] [trading-pair]",
+		Short: "Add a synthetic order book for a trading pair to a genesis file",
+		Long: `Add a synthetic order book for a trading pair to a genesis file.
+
+Every price level on both sides gets one GTE limit order, placed by a set of trader
+accounts derived from the trading pair. The frozen stock and money of the orders
+are added to the traders' accounts and to the supply of the tokens, so the chain
+starts with consistent balances. Both tokens must already exist in the genesis file,
+the market is created if it does not exist.
+
+With --import, the orders exported by 'cetdev export-orderbook' are used instead
+of generated ones.
+
+Example:
+	cetdev gen-orderbook ~/.cetd/config/genesis.json abc/cet --depth=1000 --mid-price=1.5 --price-step=0.001 --distribution=pyramid
+`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			genFile, pair := args[0], args[1]
+			if !market.IsValidTradingPair(strings.Split(pair, market.SymbolSeparator)) {
+				return fmt.Errorf("invalid trading pair: %s", pair)
+			}
+			genDoc, err := tm.GenesisDocFromFile(genFile)
+			if err != nil {
+				return err
+			}
+			var genState app.GenesisState
+			if err = cdc.UnmarshalJSON(genDoc.AppState, &genState); err != nil {
+				return err
+			}
+
+			var orders []*market.Order
+			if file := viper.GetString(flagImport); file != "" {
+				orders, err = readOrders(cdc, file, pair)
+			} else {
+				var conf orderBookConfig
+				if conf, err = getOrderBookConfig(); err == nil {
+					orders = genOrders(pair, conf)
+				}
+			}
+			if err != nil {
+				return err
+			}
+			if err = addOrderBook(&genState, pair, orders, viper.GetInt(flagPricePrecision)); err != nil {
+				return err
+			}
+
+			if genDoc.AppState, err = codec.MarshalJSONIndent(cdc, genState); err != nil {
+				return err
+			}
+			if err = genDoc.SaveAs(genFile); err != nil {
+				return err
+			}
+			fmt.Printf("%d orders of %s added to %s\n", len(orders), pair, genFile)
+			return nil
+		},
+	}
+
+	cmd.Flags().Int(flagDepth, 100, "number of price levels on each side of the book")
+	cmd.Flags().Int(flagTraders, 10, "number of trader accounts the orders are spread over")
+	cmd.Flags().String(flagMidPrice, "1", "price between the best bid and the best ask")
+	cmd.Flags().String(flagPriceStep, "0.01", "price difference between two adjacent levels")
+	cmd.Flags().Int64(flagMinQuantity, 1e8, "smallest quantity of an order")
+	cmd.Flags().Int64(flagMaxQuantity, 1e10, "largest quantity of an order")
+	cmd.Flags().String(flagDistribution, distributionRandom,
+		"how quantities are spread over the levels: flat (max quantity everywhere), random (uniform between min and max), pyramid (growing from min at the mid price to max at the last level)")
+	cmd.Flags().Int64(flagSeed, 0, "seed of the random generator")
+	cmd.Flags().Int(flagPricePrecision, 8, "price precision of the market, if it is created")
+	cmd.Flags().String(flagImport, "", "read the orders from a file written by export-orderbook")
+	return cmd
+}
+
+func ExportOrderBookCmd(cdc *codec.Codec) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export-orderbook [trading-pair]",
+		Short: "Export all the orders of a trading pair from a running node to JSON",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cliCtx := context.NewCLIContext().WithCodec(cdc)
+			route := fmt.Sprintf("custom/%s/%s", market.StoreKey, "orders-in-market")
+			param := struct{ TradingPair string }{args[0]}
+			res, _, err := cliCtx.QueryWithData(route, cdc.MustMarshalJSON(param))
+			if err != nil {
+				return err
+			}
+
+			var orders []*market.Order
+			if err = cdc.UnmarshalJSON(res, &orders); err != nil {
+				return err
+			}
+			bz, err := codec.MarshalJSONIndent(cdc, orders)
+			if err != nil {
+				return err
+			}
+			if output := viper.GetString(flagOutFile); output != "" {
+				return ioutil.WriteFile(output, bz, 0644)
+			}
+			fmt.Println(string(bz))
+			return nil
+		},
+	}
+
+	cmd.Flags().String(flagOutFile, "", "write the orders to this file instead of stdout")
+	return flags.GetCommands(cmd)[0]
+}
+
+func getOrderBookConfig() (conf orderBookConfig, err error) {
+	conf = orderBookConfig{
+		depth:        viper.GetInt(flagDepth),
+		traders:      viper.GetInt(flagTraders),
+		minQuantity:  viper.GetInt64(flagMinQuantity),
+		maxQuantity:  viper.GetInt64(flagMaxQuantity),
+		distribution: viper.GetString(flagDistribution),
+		seed:         viper.GetInt64(flagSeed),
+	}
+	if conf.midPrice, err = sdk.NewDecFromStr(viper.GetString(flagMidPrice)); err != nil {
+		return conf, fmt.Errorf("invalid %s: %s", flagMidPrice, err)
+	}
+	if conf.priceStep, err = sdk.NewDecFromStr(viper.GetString(flagPriceStep)); err != nil {
+		return conf, fmt.Errorf("invalid %s: %s", flagPriceStep, err)
+	}
+
+	if conf.depth <= 0 || conf.traders <= 0 {
+		return conf, fmt.Errorf("%s and %s must be positive", flagDepth, flagTraders)
+	}
+	if conf.minQuantity <= 0 || conf.maxQuantity < conf.minQuantity {
+		return conf, fmt.Errorf("%s must be positive and not greater than %s", flagMinQuantity, flagMaxQuantity)
+	}
+	if !conf.priceStep.IsPositive() || !conf.midPrice.GT(conf.priceStep.MulInt64(int64(conf.depth))) {
+		return conf, fmt.Errorf("%s must be positive and %s must be greater than %s * %s",
+			flagPriceStep, flagMidPrice, flagPriceStep, flagDepth)
+	}
+	switch conf.distribution {
+	case distributionFlat, distributionRandom, distributionPyramid:
+	default:
+		return conf, fmt.Errorf("unknown distribution: %s", conf.distribution)
+	}
+	return conf, nil
+}
+
+// genOrders places one bid and one ask on each level, level i being i price steps away
+// from the mid price. The orders are dealt to the traders round-robin.
+func genOrders(pair string, conf orderBookConfig) []*market.Order {
+	r := rand.New(rand.NewSource(conf.seed))
+	traders := make([]sdk.AccAddress, conf.traders)
+	for i := range traders {
+		traders[i] = traderAddress(pair, i)
+	}
+
+	orders := make([]*market.Order, 0, 2*conf.depth)
+	for level := 1; level <= conf.depth; level++ {
+		delta := conf.priceStep.MulInt64(int64(level))
+		for _, side := range []byte{market.BUY, market.SELL} {
+			price := conf.midPrice.Sub(delta)
+			if side == market.SELL {
+				price = conf.midPrice.Add(delta)
+			}
+			quantity := genQuantity(r, conf, level)
+			orders = append(orders, &market.Order{
+				Sender:      traders[len(orders)%len(traders)],
+				TradingPair: pair,
+				OrderType:   market.LimitOrder,
+				Price:       price,
+				Quantity:    quantity,
+				Side:        side,
+				TimeInForce: market.GTE,
+				ExistBlocks: market.DefaultParams().GTEOrderLifetime,
+				LeftStock:   quantity,
+			})
+		}
+	}
+	return orders
+}
+
+func genQuantity(r *rand.Rand, conf orderBookConfig, level int) int64 {
+	switch conf.distribution {
+	case distributionFlat:
+		return conf.maxQuantity
+	case distributionPyramid:
+		if conf.depth == 1 {
+			return conf.minQuantity
+		}
+		return conf.minQuantity + (conf.maxQuantity-conf.minQuantity)*int64(level-1)/int64(conf.depth-1)
+	default:
+		return conf.minQuantity + r.Int63n(conf.maxQuantity-conf.minQuantity+1)
+	}
+}
+
+func traderAddress(pair string, i int) sdk.AccAddress {
+	return sdk.AccAddress(crypto.AddressHash([]byte(fmt.Sprintf("orderbook/%s/%d", pair, i))))
+}
+
+func readOrders(cdc *codec.Codec, file, pair string) ([]*market.Order, error) {
+	bz, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+	var orders []*market.Order
+	if err = cdc.UnmarshalJSON(bz, &orders); err != nil {
+		return nil, err
+	}
+	for _, order := range orders {
+		if order.TradingPair != pair {
+			return nil, fmt.Errorf("order %s belongs to %s, not %s", order.OrderID(), order.TradingPair, pair)
+		}
+	}
+	return orders, nil
+}
+
+// addOrderBook adds the orders and the funds they freeze to the genesis state.
+// The sequences of the orders are reassigned after the traders' account sequences,
+// so the traders can keep sending transactions once the chain starts. Only the
+// remaining stock or money is frozen, the fees of imported orders are dropped.
+func addOrderBook(genState *app.GenesisState, pair string, orders []*market.Order, pricePrecision int) error {
+	stock, money := market.SplitSymbol(pair)
+	tokens := make(map[string]int)
+	for i, token := range genState.AssetData.Tokens {
+		tokens[token.GetSymbol()] = i
+	}
+	for _, symbol := range []string{stock, money} {
+		if _, ok := tokens[symbol]; !ok {
+			return fmt.Errorf("token %s does not exist in genesis", symbol)
+		}
+	}
+	addMarketIfAbsent(genState, stock, money, byte(pricePrecision), orders)
+
+	accounts := make(map[string]int)
+	for i, acc := range genState.Accounts {
+		accounts[acc.Address.String()] = i
+	}
+	accountXs := make(map[string]int)
+	for i, accx := range genState.AuthXData.AccountXs {
+		accountXs[accx.Address.String()] = i
+	}
+
+	total := sdk.NewCoins()
+	for _, order := range orders {
+		idx, ok := accounts[order.Sender.String()]
+		if !ok {
+			idx = len(genState.Accounts)
+			accounts[order.Sender.String()] = idx
+			genState.Accounts = append(genState.Accounts, genaccounts.GenesisAccount{
+				Address: order.Sender,
+				Coins:   sdk.NewCoins(),
+			})
+		}
+		order.Sequence = genState.Accounts[idx].Sequence
+		order.Identify = 0
+		order.FrozenCommission, order.FrozenFeatureFee, order.FrozenFee = 0, 0, 0
+		genState.Accounts[idx].Sequence++
+
+		frozen := sdk.NewCoins(sdk.NewCoin(stock, sdk.NewInt(order.LeftStock)))
+		if order.Side == market.BUY {
+			order.Freeze = order.Price.MulInt64(order.LeftStock).Ceil().RoundInt64()
+			frozen = sdk.NewCoins(sdk.NewCoin(money, sdk.NewInt(order.Freeze)))
+		} else {
+			order.Freeze = order.LeftStock
+		}
+		total = total.Add(frozen)
+
+		idx, ok = accountXs[order.Sender.String()]
+		if !ok {
+			idx = len(genState.AuthXData.AccountXs)
+			accountXs[order.Sender.String()] = idx
+			genState.AuthXData.AccountXs = append(genState.AuthXData.AccountXs,
+				authx.NewAccountXWithAddress(order.Sender))
+		}
+		accx := &genState.AuthXData.AccountXs[idx]
+		accx.FrozenCoins = accx.FrozenCoins.Add(frozen)
+	}
+
+	for _, coin := range total {
+		token := genState.AssetData.Tokens[tokens[coin.Denom]]
+		if err := token.SetTotalSupply(token.GetTotalSupply().Add(coin.Amount)); err != nil {
+			return err
+		}
+	}
+	// the frozen coins are moved into the authx module account after the supply has been
+	// initialized, so an empty supply, which would be summed up from the accounts, is set here
+	if genState.Supply.Supply.Empty() {
+		for _, acc := range genState.Accounts {
+			genState.Supply.Supply = genState.Supply.Supply.Add(acc.Coins)
+		}
+	}
+	genState.Supply.Supply = genState.Supply.Supply.Add(total)
+	genState.MarketData.Orders = append(genState.MarketData.Orders, orders...)
+	return genState.MarketData.Validate()
+}
+
+func addMarketIfAbsent(genState *app.GenesisState, stock, money string, pricePrecision byte, orders []*market.Order) {
+	for _, info := range genState.MarketData.MarketInfos {
+		if info.Stock == stock && info.Money == money {
+			return
+		}
+	}
+	genState.MarketData.MarketInfos = append(genState.MarketData.MarketInfos, market.MarketInfo{
+		Stock:             stock,
+		Money:             money,
+		PricePrecision:    pricePrecision,
+		LastExecutedPrice: midPrice(orders),
+	})
+}
+
+// midPrice returns the middle of the best bid and the best ask, or the best price
+// on the only side which has orders.
+func midPrice(orders []*market.Order) sdk.Dec {
+	var bestBid, bestAsk *sdk.Dec
+	for _, order := range orders {
+		price := order.Price
+		if order.Side == market.BUY && (bestBid == nil || price.GT(*bestBid)) {
+			bestBid = &price
+		}
+		if order.Side == market.SELL && (bestAsk == nil || price.LT(*bestAsk)) {
+			bestAsk = &price
+		}
+	}
+	switch {
+	case bestBid != nil && bestAsk != nil:
+		return bestBid.Add(*bestAsk).QuoInt64(2)
+	case bestBid != nil:
+		return *bestBid
+	case bestAsk != nil:
+		return *bestAsk
+	default:
+		return sdk.ZeroDec()
+	}
+}
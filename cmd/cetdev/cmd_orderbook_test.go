@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	abci "github.com/tendermint/tendermint/abci/types"
+	"github.com/tendermint/tendermint/libs/log"
+	dbm "github.com/tendermint/tm-db"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	"github.com/cosmos/cosmos-sdk/x/auth"
+	"github.com/cosmos/cosmos-sdk/x/genaccounts"
+
+	"github.com/coinexchain/cet-sdk/modules/asset"
+	"github.com/coinexchain/cet-sdk/testutil"
+	dex "github.com/coinexchain/cet-sdk/types"
+	"github.com/coinexchain/dex/app"
+)
+
+func init() {
+	dex.InitSdkConfig()
+}
+
+func newOrderBookTestToken(symbol string, owner sdk.AccAddress, supply int64) asset.Token {
+	return &asset.BaseToken{
+		Name:        symbol,
+		Symbol:      symbol,
+		TotalSupply: sdk.NewInt(supply),
+		SendLock:    sdk.ZeroInt(),
+		Owner:       owner,
+		Burnable:    true,
+		Identity:    asset.TestIdentityString,
+		TotalBurn:   sdk.ZeroInt(),
+		TotalMint:   sdk.ZeroInt(),
+	}
+}
+
+func newOrderBookTestGenesis(owner sdk.AccAddress) app.GenesisState {
+	genState := app.NewDefaultGenesisState()
+	genState.StakingData.Params.BondDenom = dex.DefaultBondDenom
+	genState.AssetData.Tokens = append(genState.AssetData.Tokens,
+		newOrderBookTestToken(dex.CET, owner, 1e16), newOrderBookTestToken("abc", owner, 1e16))
+	genState.Accounts = append(genState.Accounts, genaccounts.NewGenesisAccount(&auth.BaseAccount{
+		Address:  owner,
+		Coins:    sdk.NewCoins(sdk.NewInt64Coin(dex.CET, 1e16), sdk.NewInt64Coin("abc", 1e16)),
+		Sequence: 5,
+	}))
+	return genState
+}
+
+func TestAddOrderBook(t *testing.T) {
+	pair := "abc/cet"
+	for _, distribution := range []string{distributionFlat, distributionRandom, distributionPyramid} {
+		_, _, owner := testutil.KeyPubAddr()
+		genState := newOrderBookTestGenesis(owner)
+		conf := orderBookConfig{
+			depth:        20,
+			traders:      3,
+			midPrice:     sdk.MustNewDecFromStr("1.5"),
+			priceStep:    sdk.MustNewDecFromStr("0.01"),
+			minQuantity:  1e8,
+			maxQuantity:  1e10,
+			distribution: distribution,
+			seed:         1,
+		}
+		orders := genOrders(pair, conf)
+		require.Equal(t, 2*conf.depth, len(orders), distribution)
+		// one of the traders already has sent transactions
+		orders[0].Sender = owner
+		require.Nil(t, addOrderBook(&genState, pair, orders, 8), distribution)
+
+		// the supply is made of the coins of the accounts and their frozen coins
+		total := sdk.NewCoins()
+		sequences := make(map[string]uint64)
+		for _, acc := range genState.Accounts {
+			total = total.Add(acc.Coins)
+			sequences[acc.Address.String()] = acc.Sequence
+		}
+		for _, accx := range genState.AuthXData.AccountXs {
+			total = total.Add(accx.FrozenCoins)
+		}
+		require.Equal(t, total, genState.Supply.Supply, distribution)
+		for _, token := range genState.AssetData.Tokens {
+			require.Equal(t, total.AmountOf(token.GetSymbol()), token.GetTotalSupply(), distribution)
+		}
+
+		ids := make(map[string]bool)
+		for _, order := range genState.MarketData.Orders {
+			require.True(t, order.Sequence < sequences[order.Sender.String()], distribution)
+			require.True(t, order.Sequence >= 5 || !order.Sender.Equals(owner), distribution)
+			require.False(t, ids[order.OrderID()], distribution)
+			ids[order.OrderID()] = true
+		}
+		require.Equal(t, 1, len(genState.MarketData.MarketInfos))
+
+		// the chain boots, crisis asserts all the invariants in InitGenesis
+		cdc := app.MakeCodec()
+		genStateBytes := cdc.MustMarshalJSON(genState)
+		var genesis map[string]json.RawMessage
+		require.Nil(t, json.Unmarshal(genStateBytes, &genesis))
+		require.Nil(t, app.ModuleBasics.ValidateGenesis(genesis), distribution)
+		cetApp := app.NewCetChainApp(log.NewNopLogger(), dbm.NewMemDB(), nil, true, 0)
+		require.NotPanics(t, func() {
+			cetApp.InitChain(abci.RequestInitChain{ChainId: "orderbook", AppStateBytes: genStateBytes})
+		}, distribution)
+	}
+}
@@ -46,6 +46,8 @@ func createRootCmd(cdc *codec.Codec) *cobra.Command {
 		DefaultParamsCmd(),
 		CosmosHubParamsCmd(cdc),
 		RestEndpointsCmd(registerRoutes),
+		GenOrderBookCmd(cdc),
+		ExportOrderBookCmd(cdc),
 		//ShowCommandTreeCmd(),
 	)
 
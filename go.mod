@@ -5,6 +5,7 @@ go 1.13
 require (
 	github.com/coinexchain/cet-sdk v0.2.17-0.20200422093521-1a8e2c0d4d8c
 	github.com/coinexchain/codon v0.0.0-20191012070227-3ee72dde596c
+	github.com/coinexchain/cosmos-utils v0.0.0-20200109031554-f15ba3b1d6a7
 	github.com/coinexchain/randsrc v0.0.0-20191012073615-acfab7318ec6
 	github.com/coinexchain/trade-server v0.2.8-0.20200423021423-12d59229ce5a
 	github.com/cosmos/cosmos-sdk v0.37.4